@@ -0,0 +1,89 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Command xbo helps to sanity-check BackOff policies before they are
+// deployed.
+//
+// Usage:
+//
+//	xbo plan '<spec or JSON>' [-n 20] [-format table|csv|bar] [-seed 1] [-runs 1]
+//	xbo sim '<spec or JSON>' ['<spec or JSON>' ...] [-clients 1000] [-capacity 100] ...
+//
+// A spec is a pipeline of stages separated by "|", starting with a
+// generator and followed by any number of decorators, e.g.
+//
+//	exponential(100ms, 1.0) | jitter(25, 25) | ceiling(10s) | attempts(10)
+//
+// The same pipeline can be given as a JSON array of stages, e.g.
+//
+//	[{"type": "exponential", "initial": "100ms", "increase": 1.0},
+//	 {"type": "jitter", "under": 25, "over": 25}]
+//
+// Generators: constant(d), exponential(initial, increase), loop(d, ...),
+// limit(d, ...), echo(d, ...), decorrelated(base, max).
+//
+// Decorators: jitter(under, over), ceiling(d), floor(d), attempts(n),
+// elapsed(d).
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: xbo <command> [arguments]
+
+commands:
+  plan    preview the schedule produced by a policy
+  sim     compare how policies behave when many clients retry at once
+`
+
+// run dispatches to the sub-command, and gives back the exit code
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "plan":
+		err = plan(args[1:], stdout, stderr)
+	case "sim":
+		err = simulation(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command: %q\n%s", args[0], usage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "xbo %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,304 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+// step is a single attempt in a schedule
+type step struct {
+	delay      time.Duration
+	cumulative time.Duration
+}
+
+// schedule is what a policy produced in a single run
+type schedule struct {
+	steps   []step
+	stopped bool
+}
+
+// percentiles are the cumulative times (across many runs) at one attempt
+type percentiles struct {
+	reached       int
+	p50, p90, p99 time.Duration
+}
+
+func plan(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 20, "maximum number of attempts to show")
+	format := fs.String("format", "table", "output format: table, csv or bar")
+	seed := fs.Int64("seed", 1, "seed for jitter, for reproducible output")
+	runs := fs.Int("runs", 1, "number of runs, for percentile columns")
+
+	specs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(specs) != 1 {
+		return fmt.Errorf("exactly one policy spec is required: %q", specs)
+	}
+	if *n < 1 {
+		return fmt.Errorf("-n must be at least 1: %d", *n)
+	}
+	if *runs < 1 {
+		return fmt.Errorf("-runs must be at least 1: %d", *runs)
+	}
+
+	p, err := parsePolicy(specs[0])
+	if err != nil {
+		return err
+	}
+
+	var scheds []schedule
+	for run := 0; run < *runs; run++ {
+		sched, err := simulate(p, runSeed(*seed, run), *n)
+		if err != nil {
+			return err
+		}
+		scheds = append(scheds, sched)
+	}
+
+	switch *format {
+	case "table":
+		return writeTable(stdout, scheds)
+	case "csv":
+		return writeCSV(stdout, scheds)
+	case "bar":
+		return writeBars(stdout, scheds[0])
+	}
+	return fmt.Errorf("unknown format: %q", *format)
+}
+
+// parseArgs allows the positional arguments to be mixed in with the
+// flags, and gives them back
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var result []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		result = append(result, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("policy spec is required")
+	}
+	return result, nil
+}
+
+// runSeed spreads out the seeds for each run, so they don't overlap with
+// the per-stage offsets applied when building a policy
+func runSeed(seed int64, run int) int64 {
+	return seed + int64(run)<<16
+}
+
+// simulate walks through (up to) n attempts of the policy, in virtual time
+func simulate(p policy, seed int64, n int) (schedule, error) {
+	var result schedule
+	var now time.Duration
+	bo, err := p.build(seed, func() time.Duration { return now })
+	if err != nil {
+		return result, err
+	}
+
+	_, err = bo.Next(true)
+	if err != nil {
+		return result, err
+	}
+
+	for ix := 0; ix < n; ix++ {
+		dur, err := bo.Next(false)
+		if errors.Is(err, xbo.ErrStop) {
+			result.stopped = true
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		now = later(now, dur)
+		result.steps = append(result.steps, step{delay: dur, cumulative: now})
+	}
+	return result, nil
+}
+
+// summarize calculates the percentiles of the cumulative time for each
+// attempt, across all the runs that got that far
+func summarize(scheds []schedule) []percentiles {
+	var result []percentiles
+	for ix := 0; ; ix++ {
+		var cums []time.Duration
+		for _, sched := range scheds {
+			if ix < len(sched.steps) {
+				cums = append(cums, sched.steps[ix].cumulative)
+			}
+		}
+		if len(cums) == 0 {
+			return result
+		}
+		sort.Slice(cums, func(i, j int) bool { return cums[i] < cums[j] })
+		result = append(result, percentiles{
+			reached: len(cums),
+			p50:     percentile(cums, 0.50),
+			p90:     percentile(cums, 0.90),
+			p99:     percentile(cums, 0.99),
+		})
+	}
+}
+
+// percentile uses the nearest-rank method on already-sorted values
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// rows lays out the schedule of the first run, plus percentile columns
+// when there has been more than one run
+func rows(scheds []schedule) [][]string {
+	header := []string{"attempt", "delay", "cumulative"}
+	multi := len(scheds) > 1
+	if multi {
+		header = append(header, "reached", "p50", "p90", "p99")
+	}
+	result := [][]string{header}
+
+	first := scheds[0]
+	pcts := summarize(scheds)
+	for ix, pct := range pcts {
+		row := []string{strconv.Itoa(ix + 1), "-", "-"}
+		if ix < len(first.steps) {
+			row[1] = first.steps[ix].delay.String()
+			row[2] = first.steps[ix].cumulative.String()
+		}
+		if multi {
+			row = append(row,
+				strconv.Itoa(pct.reached),
+				pct.p50.String(),
+				pct.p90.String(),
+				pct.p99.String(),
+			)
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// stopLine describes where (if anywhere) the runs stopped
+func stopLine(scheds []schedule) string {
+	stopped := 0
+	for _, sched := range scheds {
+		if sched.stopped {
+			stopped++
+		}
+	}
+	first := scheds[0]
+	line := fmt.Sprintf("no stop within %d attempts", len(first.steps))
+	if first.stopped {
+		line = fmt.Sprintf("stop after %d attempts", len(first.steps))
+	}
+	if len(scheds) > 1 {
+		line = fmt.Sprintf("%s (stopped in %d of %d runs)", line, stopped, len(scheds))
+	}
+	return line
+}
+
+func writeTable(w io.Writer, scheds []schedule) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, row := range rows(scheds) {
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, stopLine(scheds))
+	return err
+}
+
+func writeCSV(w io.Writer, scheds []schedule) error {
+	cw := csv.NewWriter(w)
+	records := rows(scheds)
+	if scheds[0].stopped {
+		stop := make([]string, len(records[0]))
+		stop[0] = "stop"
+		records = append(records, stop)
+	}
+	err := cw.WriteAll(records)
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+const barWidth = 50
+
+func writeBars(w io.Writer, sched schedule) error {
+	var max time.Duration
+	for _, s := range sched.steps {
+		if s.delay > max {
+			max = s.delay
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for ix, s := range sched.steps {
+		width := 0
+		if max > 0 {
+			width = int(math.Round(float64(s.delay) / float64(max) * barWidth))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t|%s\n", ix+1, s.delay, strings.Repeat("#", width))
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, stopLine([]schedule{sched}))
+	return err
+}
+
+// later adds the delay to the virtual time, topping out at the largest
+// duration we can represent, rather than overflowing
+func later(now time.Duration, dur time.Duration) time.Duration {
+	if dur > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + dur
+}
@@ -0,0 +1,172 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanOutput(t *testing.T) {
+	spec := "exponential(100ms, 1.0) | attempts(3)"
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{spec},
+			`  attempt  delay  cumulative
+        1  100ms       100ms
+        2  200ms       300ms
+        3  400ms       700ms
+stop after 3 attempts
+`,
+		},
+		{
+			[]string{"-format", "csv", spec, "-n", "2"},
+			`attempt,delay,cumulative
+1,100ms,100ms
+2,200ms,300ms
+`,
+		},
+		{
+			[]string{spec, "-format", "bar"},
+			`1 100ms |` + strings.Repeat("#", 13) + `
+2 200ms |` + strings.Repeat("#", 25) + `
+3 400ms |` + strings.Repeat("#", 50) + `
+stop after 3 attempts
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"plan"}, tc.args...), &stdout, &stderr)
+		if code != 0 {
+			t.Errorf("unexpected exit %d: %s", code, stderr.String())
+		}
+		if stdout.String() != tc.expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, stdout.String())
+		}
+	}
+}
+
+func TestPlanReproducible(t *testing.T) {
+	spec := "exponential(100ms, 1.0) | jitter(50, 50) | attempts(10)"
+	outputs := map[string]int{}
+	for _, seed := range []string{"1", "1", "2"} {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"plan", spec, "-seed", seed, "-runs", "20"}, &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+		}
+		outputs[stdout.String()]++
+	}
+
+	// The two runs with the same seed should match, the other one shouldn't
+	if len(outputs) != 2 {
+		t.Errorf("expected 2 distinct outputs: %d", len(outputs))
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	argss := [][]string{
+		{},
+		{"bogus"},
+		{"plan"},
+		{"plan", "constant(1s)", "extra"},
+		{"plan", "constant(1s)", "-n", "0"},
+		{"plan", "constant(1s)", "-runs", "0"},
+		{"plan", "constant(1s)", "-format", "pie"},
+		{"plan", "constant(1s)", "-bogus"},
+	}
+	for _, args := range argss {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		if code == 0 {
+			t.Errorf("expected failure: %q", args)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("expected complaint: %q", args)
+		}
+	}
+}
+
+func TestPlanSaturates(t *testing.T) {
+	p, err := parsePolicy("exponential(1h, 10)")
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	sched, err := simulate(p, 1, 12)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if len(sched.steps) != 12 {
+		t.Fatalf("expected 12: %d", len(sched.steps))
+	}
+
+	// The cumulative time never goes backwards, even once it's too big
+	var last time.Duration
+	for ix, s := range sched.steps {
+		if s.cumulative < last {
+			t.Errorf("%d: went backwards: %v < %v", ix, s.cumulative, last)
+		}
+		last = s.cumulative
+	}
+	if last != time.Duration(math.MaxInt64) {
+		t.Errorf("expected %v: %v", time.Duration(math.MaxInt64), last)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var scheds []schedule
+	for ix := 1; ix <= 100; ix++ {
+		sched := schedule{steps: []step{{
+			delay:      time.Duration(ix),
+			cumulative: time.Duration(ix),
+		}}}
+		// Only half get to the second attempt
+		if ix%2 == 0 {
+			sched.steps = append(sched.steps, step{
+				delay:      time.Duration(ix),
+				cumulative: time.Duration(2 * ix),
+			})
+		}
+		scheds = append(scheds, sched)
+	}
+
+	pcts := summarize(scheds)
+	expected := []percentiles{
+		{reached: 100, p50: 50, p90: 90, p99: 99},
+		{reached: 50, p50: 100, p90: 180, p99: 200},
+	}
+	if len(pcts) != len(expected) {
+		t.Fatalf("expected %d: %d", len(expected), len(pcts))
+	}
+	for ix := range expected {
+		if pcts[ix] != expected[ix] {
+			t.Errorf("expected %v: %v", expected[ix], pcts[ix])
+		}
+	}
+}
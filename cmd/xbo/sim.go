@@ -0,0 +1,139 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nelz9999/go-xbo/sim"
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+func simulation(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("sim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	clients := fs.Int("clients", 1000, "number of clients")
+	capacity := fs.Int("capacity", 100, "successful requests the server can handle per bucket")
+	bucket := fs.Duration("bucket", time.Second, "width of each time bucket")
+	outageStart := fs.Duration("outage-start", 0, "when the server outage starts")
+	outageEnd := fs.Duration("outage-end", time.Second*30, "when the server outage ends")
+	arrival := fs.Duration("arrival", 0, "window over which clients make their first attempt")
+	horizon := fs.Duration("horizon", time.Minute*10, "how much virtual time to simulate")
+	seed := fs.Int64("seed", 1, "seed for jitter and arrivals, for reproducible output")
+	buckets := fs.Bool("buckets", false, "also show the attempts made in each bucket")
+
+	specs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var results []*sim.Result
+	for _, spec := range specs {
+		p, err := parsePolicy(spec)
+		if err != nil {
+			return err
+		}
+
+		result, err := sim.Run(sim.Config{
+			Clients:     *clients,
+			Capacity:    *capacity,
+			Bucket:      *bucket,
+			OutageStart: *outageStart,
+			OutageEnd:   *outageEnd,
+			Arrival:     *arrival,
+			Horizon:     *horizon,
+			Seed:        *seed,
+			Policy: func(client int, now sim.Clock) (xbo.BackOff, error) {
+				return p.build(runSeed(*seed, client), clock(now))
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", spec, err)
+		}
+		results = append(results, result)
+	}
+
+	if *buckets {
+		err = writeBuckets(stdout, results)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout)
+	}
+	return writeSummary(stdout, specs, results)
+}
+
+func writeSummary(w io.Writer, specs []string, results []*sim.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tattempts\tpeak\tsucceeded\tgave up\tpending\trecovery\tpolicy")
+	for ix, result := range results {
+		peak := 0
+		for _, b := range result.Buckets {
+			if b.Attempts > peak {
+				peak = b.Attempts
+			}
+		}
+		recovery := "-"
+		if result.Recovered {
+			recovery = result.Recovery.String()
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			ix+1, result.Attempts, peak, result.Succeeded,
+			result.GaveUp, result.Pending, recovery,
+			strings.Join(strings.Fields(specs[ix]), " "),
+		)
+	}
+	return tw.Flush()
+}
+
+// writeBuckets shows the attempts made by each policy side-by-side, one row
+// per bucket, leaving off the trailing buckets where nothing happened
+func writeBuckets(w io.Writer, results []*sim.Result) error {
+	last := 0
+	for _, result := range results {
+		for ix, b := range result.Buckets {
+			if b.Attempts > 0 && ix > last {
+				last = ix
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{"bucket"}
+	for ix := range results {
+		header = append(header, "#"+strconv.Itoa(ix+1))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for ix := 0; ix <= last; ix++ {
+		row := []string{results[0].Buckets[ix].Start.String()}
+		for _, result := range results {
+			row = append(row, strconv.Itoa(result.Buckets[ix].Attempts))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSimOutput(t *testing.T) {
+	args := []string{
+		"sim", "constant(1s)", "-clients", "100", "-capacity", "10",
+		"-outage-end", "5s", "-horizon", "1m", "-buckets",
+		"limit(1s, 1s) | attempts(1)",
+	}
+	expected := `  bucket   #1   #2
+      0s  100  100
+      1s  100  100
+      2s  100    0
+      3s  100    0
+      4s  100    0
+      5s  100    0
+      6s   90    0
+      7s   80    0
+      8s   70    0
+      9s   60    0
+     10s   50    0
+     11s   40    0
+     12s   30    0
+     13s   20    0
+     14s   10    0
+
+#  attempts  peak  succeeded  gave up  pending  recovery  policy
+1  1050      100   100        0        0        9s        constant(1s)
+2  200       100   0          100      0        0s        limit(1s, 1s) | attempts(1)
+`
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+}
+
+func TestSimErrors(t *testing.T) {
+	argss := [][]string{
+		{"sim"},
+		{"sim", "bogus(1s)"},
+		{"sim", "constant(1s)", "-clients", "0"},
+		{"sim", "constant(1s)", "-outage-start", "1m", "-outage-end", "1s"},
+		{"sim", "constant(0s)", "-capacity", "0"},
+	}
+	for _, args := range argss {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		if code == 0 {
+			t.Errorf("expected failure: %q", args)
+		}
+		if !strings.Contains(stderr.String(), "xbo sim") {
+			t.Errorf("expected complaint: %q", stderr.String())
+		}
+	}
+}
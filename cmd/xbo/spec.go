@@ -0,0 +1,280 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nelz9999/go-xbo/sim"
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+// stage is a single step in the pipeline of a policy; a generator or
+// a decorator. Only the fields relevant to the Type are consulted.
+type stage struct {
+	Type     string   `json:"type"`
+	Delay    string   `json:"delay,omitempty"`
+	Delays   []string `json:"delays,omitempty"`
+	Initial  string   `json:"initial,omitempty"`
+	Increase float64  `json:"increase,omitempty"`
+	Under    uint8    `json:"under,omitempty"`
+	Over     uint8    `json:"over,omitempty"`
+	Bound    string   `json:"bound,omitempty"`
+	Attempts uint32   `json:"attempts,omitempty"`
+}
+
+// policy is a parsed, validated pipeline of stages that can be used to
+// build as many (independent) BackOffs as needed.
+type policy []stage
+
+// clock reports how much virtual time has passed, so that time-based
+// decorators can be previewed without actually waiting.
+type clock func() time.Duration
+
+// epoch anchors virtual time, for decorators that want to tell the time
+var epoch = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// time gives the virtual time as a time.Time (see xbo.BoundClock)
+func (c clock) time() time.Time {
+	return epoch.Add(c())
+}
+
+var stagePattern = regexp.MustCompile(`^(\w+)\s*\((.*)\)$`)
+
+// parsePolicy accepts either the compact spec, or the JSON equivalent.
+func parsePolicy(spec string) (policy, error) {
+	spec = strings.TrimSpace(spec)
+	var result policy
+	switch {
+	case spec == "":
+		return nil, fmt.Errorf("empty policy")
+	case strings.HasPrefix(spec, "["):
+		err := json.Unmarshal([]byte(spec), &result)
+		if err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(spec, "{"):
+		var s stage
+		err := json.Unmarshal([]byte(spec), &s)
+		if err != nil {
+			return nil, err
+		}
+		result = policy{s}
+	default:
+		for _, part := range strings.Split(spec, "|") {
+			s, err := parseStage(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+	}
+
+	// Building it once is the simplest way to be sure it's valid
+	_, err := result.build(0, func() time.Duration { return 0 })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseStage(part string) (stage, error) {
+	s := stage{}
+	m := stagePattern.FindStringSubmatch(part)
+	if m == nil {
+		return s, fmt.Errorf("malformed stage: %q", part)
+	}
+	s.Type = m[1]
+
+	var args []string
+	for _, arg := range strings.Split(m[2], ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+
+	want := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d argument(s): %q", s.Type, n, part)
+		}
+		return nil
+	}
+
+	var err error
+	switch s.Type {
+	case "constant":
+		if err = want(1); err == nil {
+			s.Delay = args[0]
+		}
+	case "loop", "limit", "echo":
+		s.Delays = args
+	case "exponential":
+		if err = want(2); err == nil {
+			s.Initial = args[0]
+			s.Increase, err = strconv.ParseFloat(args[1], 64)
+		}
+	case "decorrelated":
+		if err = want(2); err == nil {
+			s.Initial = args[0]
+			s.Bound = args[1]
+		}
+	case "jitter":
+		if err = want(2); err == nil {
+			s.Under, err = parsePercent(args[0])
+			if err == nil {
+				s.Over, err = parsePercent(args[1])
+			}
+		}
+	case "ceiling", "floor", "elapsed":
+		if err = want(1); err == nil {
+			s.Bound = args[0]
+		}
+	case "attempts":
+		if err = want(1); err == nil {
+			var n uint64
+			n, err = strconv.ParseUint(args[0], 10, 32)
+			s.Attempts = uint32(n)
+		}
+	default:
+		err = fmt.Errorf("unknown stage: %q", s.Type)
+	}
+	return s, err
+}
+
+func parsePercent(arg string) (uint8, error) {
+	n, err := strconv.ParseUint(arg, 10, 8)
+	return uint8(n), err
+}
+
+func parseDurations(args []string) ([]time.Duration, error) {
+	var result []time.Duration
+	for _, arg := range args {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// build creates a fresh BackOff from the policy. Jitter is seeded, so that
+// the same seed always gives the same schedule, and the elapsed decorator
+// uses the given clock rather than the wall clock.
+func (p policy) build(seed int64, now clock) (xbo.BackOff, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("empty policy")
+	}
+
+	var bo xbo.BackOff
+	for ix, s := range p {
+		if ix == 0 {
+			var err error
+			bo, err = s.generate(seed)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		dec, err := s.decorate(bo, seed+int64(ix), now)
+		if err != nil {
+			return nil, err
+		}
+		bo = dec
+	}
+	return bo, nil
+}
+
+func (s stage) generate(seed int64) (xbo.BackOff, error) {
+	switch s.Type {
+	case "constant":
+		d, err := time.ParseDuration(s.Delay)
+		if err != nil {
+			return nil, err
+		}
+		return xbo.NewConstant(d), nil
+	case "loop", "limit", "echo":
+		durs, err := parseDurations(s.Delays)
+		if err != nil {
+			return nil, err
+		}
+		if len(durs) == 0 {
+			return nil, fmt.Errorf("%s needs at least one duration", s.Type)
+		}
+		switch s.Type {
+		case "loop":
+			return xbo.NewLoop(durs, false), nil
+		case "limit":
+			return xbo.NewLimit(durs, false), nil
+		}
+		return xbo.NewEcho(durs, false), nil
+	case "exponential":
+		d, err := time.ParseDuration(s.Initial)
+		if err != nil {
+			return nil, err
+		}
+		return xbo.NewExponential(d, s.Increase)
+	case "decorrelated":
+		base, err := time.ParseDuration(s.Initial)
+		if err != nil {
+			return nil, err
+		}
+		max, err := time.ParseDuration(s.Bound)
+		if err != nil {
+			return nil, err
+		}
+		return sim.Decorrelated(base, max, rand.New(rand.NewSource(seed)))
+	}
+	return nil, fmt.Errorf("policy must start with a generator, not %q", s.Type)
+}
+
+func (s stage) decorate(bo xbo.BackOff, seed int64, now clock) (xbo.BackOff, error) {
+	switch s.Type {
+	case "jitter":
+		return xbo.NewJitter(bo,
+			xbo.JitterUnder(s.Under),
+			xbo.JitterOver(s.Over),
+			xbo.JitterRandomizer(rand.New(rand.NewSource(seed))),
+		)
+	case "attempts":
+		return xbo.NewMaxAttempts(bo, s.Attempts)
+	case "ceiling", "floor", "elapsed":
+		d, err := time.ParseDuration(s.Bound)
+		if err != nil {
+			return nil, err
+		}
+		switch s.Type {
+		case "ceiling":
+			return xbo.NewCeiling(bo, d)
+		case "floor":
+			return xbo.NewFloor(bo, d)
+		}
+		return xbo.NewElapsed(bo, d, xbo.BoundClock(now.time))
+	}
+	return nil, fmt.Errorf("%q cannot be used as a decorator", s.Type)
+}
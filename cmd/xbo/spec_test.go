@@ -0,0 +1,166 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+func TestParsePolicyErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"exponential",
+		"exponential(100ms)",
+		"exponential(soon, 1.0)",
+		"exponential(100ms, -1.0)",
+		"constant(1s) | bogus(1)",
+		"jitter(10, 10)",
+		"limit() | attempts(2)",
+		"constant(1s) | exponential(1s, 1.0)",
+		"constant(1s) | jitter(101, 0)",
+		"constant(1s) | jitter(0, 0)",
+		"constant(1s) | attempts(0)",
+		"constant(1s) | ceiling(0s)",
+		"decorrelated(1s)",
+		"decorrelated(1m, 1s)",
+		"decorrelated(1s, never)",
+		`[{"type": "constant"}]`,
+		`[{"type": "constant", "delay": "1s"}, {"type": "floor"}]`,
+		`[]`,
+		`{"type": `,
+	}
+	for _, spec := range specs {
+		p, err := parsePolicy(spec)
+		if err == nil {
+			t.Errorf("expected error: %q", spec)
+		}
+		if p != nil {
+			t.Errorf("expected nil: %v", p)
+		}
+	}
+}
+
+func TestParsePolicyEquivalence(t *testing.T) {
+	// Each set of specs should produce identical schedules
+	testCases := [][]string{
+		{
+			"exponential(100ms, 1.0) | jitter(25, 25) | ceiling(1s) | attempts(8)",
+			`[{"type": "exponential", "initial": "100ms", "increase": 1.0},
+			  {"type": "jitter", "under": 25, "over": 25},
+			  {"type": "ceiling", "bound": "1s"},
+			  {"type": "attempts", "attempts": 8}]`,
+		},
+		{
+			"constant(1s)",
+			`{"type": "constant", "delay": "1s"}`,
+			"loop(1s)",
+			"echo(1s)",
+		},
+		{
+			"limit(1ms, 2s) | floor(1s)",
+			`[{"type": "limit", "delays": ["1ms", "2s"]},
+			  {"type": "floor", "bound": "1s"}]`,
+			"loop(1s, 2s) | attempts(2)",
+		},
+		{
+			"decorrelated(100ms, 10s) | attempts(5)",
+			`[{"type": "decorrelated", "initial": "100ms", "bound": "10s"},
+			  {"type": "attempts", "attempts": 5}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var expected schedule
+		for ix, spec := range tc {
+			p, err := parsePolicy(spec)
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			sched, err := simulate(p, 42, 12)
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			if ix == 0 {
+				expected = sched
+				continue
+			}
+			if len(sched.steps) != len(expected.steps) {
+				t.Fatalf("%q expected %d: %d", spec, len(expected.steps), len(sched.steps))
+			}
+			for jx := range sched.steps {
+				if sched.steps[jx] != expected.steps[jx] {
+					t.Errorf("%q expected %v: %v", spec, expected.steps[jx], sched.steps[jx])
+				}
+			}
+			if sched.stopped != expected.stopped {
+				t.Errorf("%q expected %t: %t", spec, expected.stopped, sched.stopped)
+			}
+		}
+	}
+}
+
+func TestVirtualElapsed(t *testing.T) {
+	var now time.Duration
+	s := stage{Type: "elapsed", Bound: "3s"}
+	bo, err := s.decorate(xbo.NewConstant(time.Second), 1, func() time.Duration {
+		return now
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	for ix := 0; ix < 4; ix++ {
+		dur, err := bo.Next(false)
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+		now += dur
+	}
+
+	_, err = bo.Next(false)
+	var stop *xbo.StopError
+	if !errors.As(err, &stop) {
+		t.Fatalf("expected %v: %v", xbo.ErrStop, err)
+	}
+	expected := xbo.StopError{
+		Reason:   xbo.ReasonElapsed,
+		Source:   "Elapsed",
+		Attempts: 4,
+		Elapsed:  time.Second * 4,
+	}
+	if *stop != expected {
+		t.Errorf("expected %+v: %+v", expected, *stop)
+	}
+
+	// The reset restarts the virtual clock
+	_, err = bo.Next(true)
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+	_, err = bo.Next(false)
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
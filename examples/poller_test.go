@@ -22,6 +22,7 @@ package examples
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -114,7 +115,7 @@ func (p *poller) poll(ctx context.Context) error {
 		// a successful interaction with the polled function)
 		werr := p.w.Wait(ctx, (err == nil))
 		if werr != nil {
-			if werr == xbo.ErrStop {
+			if errors.Is(werr, xbo.ErrStop) {
 				// If there's been just too many errors in a row,
 				// we might want to choose to surface the error
 				// that was returned from the resource being polled.
@@ -1,3 +1,3 @@
 module github.com/nelz9999/go-xbo
 
-go 1.12
+go 1.23
@@ -0,0 +1,67 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sim
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+// Decorrelated creates a BackOff using the "decorrelated jitter" strategy
+// (as described on the AWS Architecture Blog), where each duration is
+// picked at random between base and three times the previous duration,
+// capped at max. It is provided here as a point of comparison for the
+// jitter in package xbo.
+//
+// The JitterRand is used as-is, so it is not concurrent-safe unless the
+// JitterRand is.
+func Decorrelated(base time.Duration, max time.Duration, r xbo.JitterRand) (xbo.BackOff, error) {
+	if base <= 0 {
+		return nil, fmt.Errorf("base must be greater than zero: %v", base)
+	}
+	if max < base {
+		return nil, fmt.Errorf("max must not be less than base: %v < %v", max, base)
+	}
+	if r == nil {
+		return nil, fmt.Errorf("nil randomizer")
+	}
+
+	prev := base
+	return xbo.BackOffFunc(func(reset bool) (time.Duration, error) {
+		if reset {
+			prev = base
+			return xbo.ZeroDuration, nil
+		}
+
+		// Don't let three times the previous overflow
+		top := max
+		if prev <= time.Duration(math.MaxInt64/3) && prev*3 < max {
+			top = prev * 3
+		}
+
+		// (Add one, because result range does not include the max number.)
+		prev = base + time.Duration(r.Int63n(int64(top-base)+1))
+		return prev, nil
+	}), nil
+}
@@ -0,0 +1,82 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sim
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+func TestDecorrelatedErrors(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inputs := []struct {
+		base time.Duration
+		max  time.Duration
+		r    xbo.JitterRand
+	}{
+		{0, time.Second, r},
+		{time.Second, time.Millisecond, r},
+		{time.Second, time.Minute, nil},
+	}
+	for _, input := range inputs {
+		bo, err := Decorrelated(input.base, input.max, input.r)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if bo != nil {
+			t.Errorf("expected nil: %v", bo)
+		}
+	}
+}
+
+func TestDecorrelated(t *testing.T) {
+	base := time.Millisecond * 100
+	max := time.Second * 10
+	bo, err := Decorrelated(base, max, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	for ix := 0; ix < 3; ix++ {
+		prev := base
+		for jx := 0; jx < 100; jx++ {
+			dur, err := bo.Next(false)
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+			if dur < base || dur > max || dur > prev*3 {
+				t.Errorf("out of range after %v: %v", prev, dur)
+			}
+			prev = dur
+		}
+
+		dur, err := bo.Next(true)
+		if dur != 0 {
+			t.Errorf("expected 0: %v", dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sim models many clients retrying against a server with finite
+// capacity, in virtual time, so that BackOff strategies can be compared
+// offline.
+//
+// Every client needs a single successful request. The server can only
+// handle so many successful requests per time bucket, and fails every
+// request during the outage window. A client that fails asks its BackOff
+// how long to wait, and tries again, until it succeeds or is told to stop.
+package sim
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+// MaxAttempts is the most attempts (across all clients) that a single Run
+// will simulate, to guard against policies that retry without any delay.
+const MaxAttempts = 1 << 22
+
+// Clock reports how much virtual time has passed since the start of the
+// simulation.
+type Clock func() time.Duration
+
+// Factory creates the BackOff for a single client. Policies that depend
+// on the passage of time should consult the Clock, rather than the wall
+// clock, as a simulation takes no real time at all.
+type Factory func(client int, now Clock) (xbo.BackOff, error)
+
+// Config describes a scenario to simulate.
+type Config struct {
+	// Clients is how many clients each need one successful request.
+	Clients int
+
+	// Capacity is how many successful requests the server can handle in
+	// each Bucket; any further requests in that Bucket fail.
+	Capacity int
+
+	// Bucket is the granularity of both the server capacity, and of
+	// the reporting.
+	Bucket time.Duration
+
+	// OutageStart and OutageEnd define the window in which the server
+	// fails every request.
+	OutageStart time.Duration
+	OutageEnd   time.Duration
+
+	// Arrival spreads out the first attempt of each client, uniformly
+	// over [0, Arrival). Zero means everyone starts at once.
+	Arrival time.Duration
+
+	// Horizon is how far into virtual time the simulation is run.
+	Horizon time.Duration
+
+	// Seed makes the arrival times reproducible.
+	Seed int64
+
+	// Policy creates the BackOff used by each client.
+	Policy Factory
+}
+
+// Bucket reports what happened within one slice of virtual time.
+type Bucket struct {
+	Start     time.Duration
+	Attempts  int
+	Successes int
+	Failures  int
+	GaveUp    int
+}
+
+// Result reports the outcome of a simulation.
+type Result struct {
+	// Buckets holds the load on the server over time, up to the Horizon.
+	Buckets []Bucket
+
+	// Attempts counts every request made, by every client.
+	Attempts int
+
+	// Succeeded, GaveUp and Pending count how each client ended up;
+	// Pending ones were still retrying when the Horizon was reached.
+	Succeeded int
+	GaveUp    int
+	Pending   int
+
+	// Recovered is true if every client had either succeeded or given
+	// up by the Horizon, in which case Recovery is how long after the end
+	// of the outage the last one of them did so.
+	Recovered bool
+	Recovery  time.Duration
+}
+
+// Run simulates the scenario described by the Config.
+func Run(cfg Config) (*Result, error) {
+	switch {
+	case cfg.Clients < 1:
+		return nil, fmt.Errorf("clients must be at least 1: %d", cfg.Clients)
+	case cfg.Capacity < 0:
+		return nil, fmt.Errorf("capacity must not be negative: %d", cfg.Capacity)
+	case cfg.Bucket <= 0:
+		return nil, fmt.Errorf("bucket must be greater than zero: %v", cfg.Bucket)
+	case cfg.Horizon <= 0:
+		return nil, fmt.Errorf("horizon must be greater than zero: %v", cfg.Horizon)
+	case cfg.Arrival < 0:
+		return nil, fmt.Errorf("arrival must not be negative: %v", cfg.Arrival)
+	case cfg.OutageEnd < cfg.OutageStart:
+		return nil, fmt.Errorf("outage must not end before it starts: %v < %v",
+			cfg.OutageEnd, cfg.OutageStart)
+	case cfg.Policy == nil:
+		return nil, fmt.Errorf("policy is required")
+	}
+
+	var now time.Duration
+	clock := Clock(func() time.Duration { return now })
+	r := rand.New(rand.NewSource(cfg.Seed))
+
+	pending := &queue{}
+	bos := make([]xbo.BackOff, cfg.Clients)
+	for ix := range bos {
+		bo, err := cfg.Policy(ix, clock)
+		if err != nil {
+			return nil, err
+		}
+		if bo == nil {
+			return nil, fmt.Errorf("policy gave client %d a nil backoff", ix)
+		}
+		bos[ix] = bo
+
+		var at time.Duration
+		if cfg.Arrival > 0 {
+			at = time.Duration(r.Int63n(int64(cfg.Arrival)))
+		}
+		heap.Push(pending, attempt{at: at, client: ix})
+	}
+
+	size := int((cfg.Horizon + cfg.Bucket - 1) / cfg.Bucket)
+	result := &Result{Buckets: make([]Bucket, size)}
+	for ix := range result.Buckets {
+		result.Buckets[ix].Start = time.Duration(ix) * cfg.Bucket
+	}
+
+	var last time.Duration
+	for pending.Len() > 0 {
+		a := heap.Pop(pending).(attempt)
+		if a.at >= cfg.Horizon {
+			// Everything else in the queue is further out still
+			result.Pending = pending.Len() + 1
+			break
+		}
+		now = a.at
+
+		result.Attempts++
+		if result.Attempts > MaxAttempts {
+			return nil, fmt.Errorf("gave up after %d attempts; is the policy waiting at all?", MaxAttempts)
+		}
+
+		b := &result.Buckets[int(now/cfg.Bucket)]
+		b.Attempts++
+		outage := now >= cfg.OutageStart && now < cfg.OutageEnd
+		if !outage && b.Successes < cfg.Capacity {
+			b.Successes++
+			result.Succeeded++
+			last = now
+			continue
+		}
+		b.Failures++
+
+		dur, err := bos[a.client].Next(false)
+		if errors.Is(err, xbo.ErrStop) {
+			b.GaveUp++
+			result.GaveUp++
+			last = now
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("client %d: %v", a.client, err)
+		}
+		heap.Push(pending, attempt{at: later(now, dur), client: a.client})
+	}
+
+	if result.Pending == 0 {
+		result.Recovered = true
+		if last > cfg.OutageEnd {
+			result.Recovery = last - cfg.OutageEnd
+		}
+	}
+	return result, nil
+}
+
+// later works out when the next attempt will be, without overflowing for
+// very long waits (which end up past any Horizon, and so are left pending)
+func later(now time.Duration, dur time.Duration) time.Duration {
+	if dur < 0 {
+		return now
+	}
+	if dur > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + dur
+}
+
+// attempt is a request that a client will make at some point in time
+type attempt struct {
+	at     time.Duration
+	client int
+}
+
+// queue orders the attempts by time, and then by client, so that every
+// simulation is deterministic
+type queue []attempt
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].client < q[j].client
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(attempt)) }
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
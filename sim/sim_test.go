@@ -0,0 +1,255 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nelz9999/go-xbo/xbo"
+)
+
+func constant(d time.Duration) Factory {
+	return func(client int, now Clock) (xbo.BackOff, error) {
+		return xbo.NewConstant(d), nil
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	good := Config{
+		Clients:  10,
+		Capacity: 10,
+		Bucket:   time.Second,
+		Horizon:  time.Minute,
+		Policy:   constant(time.Second),
+	}
+
+	mods := []func(*Config){
+		func(c *Config) { c.Clients = 0 },
+		func(c *Config) { c.Capacity = -1 },
+		func(c *Config) { c.Bucket = 0 },
+		func(c *Config) { c.Horizon = 0 },
+		func(c *Config) { c.Arrival = -1 },
+		func(c *Config) { c.OutageStart, c.OutageEnd = 2, 1 },
+		func(c *Config) { c.Policy = nil },
+		func(c *Config) {
+			c.Policy = func(int, Clock) (xbo.BackOff, error) { return nil, nil }
+		},
+		func(c *Config) {
+			c.Policy = func(int, Clock) (xbo.BackOff, error) {
+				return xbo.Ceiling(xbo.NewZero(), 0), nil
+			}
+			c.Capacity = 0
+		},
+		func(c *Config) {
+			// Retrying with no delay against a server that can't keep up
+			c.Policy = constant(0)
+			c.Capacity = 0
+		},
+	}
+
+	for ix, mod := range mods {
+		cfg := good
+		mod(&cfg)
+		result, err := Run(cfg)
+		if err == nil {
+			t.Errorf("%d expected error", ix)
+		}
+		if result != nil {
+			t.Errorf("%d expected nil: %v", ix, result)
+		}
+	}
+}
+
+func TestRunRecovery(t *testing.T) {
+	result, err := Run(Config{
+		Clients:   100,
+		Capacity:  10,
+		Bucket:    time.Second,
+		OutageEnd: time.Second * 5,
+		Horizon:   time.Minute,
+		Policy:    constant(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	// Everyone tries every second: five rounds of outage, then ten
+	// rounds to work through the backlog at ten per second
+	if result.Attempts != 500+550 {
+		t.Errorf("expected %d: %d", 500+550, result.Attempts)
+	}
+	if result.Succeeded != 100 {
+		t.Errorf("expected 100: %d", result.Succeeded)
+	}
+	if !result.Recovered {
+		t.Errorf("expected recovery")
+	}
+	if result.Recovery != time.Second*9 {
+		t.Errorf("expected %v: %v", time.Second*9, result.Recovery)
+	}
+	if len(result.Buckets) != 60 {
+		t.Fatalf("expected 60: %d", len(result.Buckets))
+	}
+
+	expected := []Bucket{
+		{Start: 0, Attempts: 100, Failures: 100},
+		{Start: time.Second * 5, Attempts: 100, Successes: 10, Failures: 90},
+		{Start: time.Second * 14, Attempts: 10, Successes: 10},
+		{Start: time.Second * 15},
+	}
+	for _, b := range expected {
+		actual := result.Buckets[int(b.Start/time.Second)]
+		if actual != b {
+			t.Errorf("expected %v: %v", b, actual)
+		}
+	}
+}
+
+func TestRunGiveUpAndPending(t *testing.T) {
+	cfg := Config{
+		Clients:   100,
+		Capacity:  100,
+		Bucket:    time.Second,
+		OutageEnd: time.Second * 10,
+		Horizon:   time.Minute,
+		Policy: func(int, Clock) (xbo.BackOff, error) {
+			return xbo.MaxAttempts(xbo.NewConstant(time.Second), 3, false), nil
+		},
+	}
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.GaveUp != 100 {
+		t.Errorf("expected 100: %d", result.GaveUp)
+	}
+	if result.Attempts != 400 {
+		t.Errorf("expected 400: %d", result.Attempts)
+	}
+	if result.Buckets[3].GaveUp != 100 {
+		t.Errorf("expected 100: %d", result.Buckets[3].GaveUp)
+	}
+	if !result.Recovered || result.Recovery != 0 {
+		t.Errorf("expected immediate recovery: %v", result.Recovery)
+	}
+
+	// Cut it off before the outage is over
+	cfg.Horizon = time.Second * 5
+	cfg.Policy = constant(time.Second)
+	result, err = Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.Pending != 100 {
+		t.Errorf("expected 100: %d", result.Pending)
+	}
+	if result.Recovered {
+		t.Errorf("unexpected recovery")
+	}
+}
+
+func TestRunSaturatingPolicy(t *testing.T) {
+	// The waits get too long to represent, and are left pending
+	result, err := Run(Config{
+		Clients:  2,
+		Capacity: 0,
+		Bucket:   time.Hour * 100000,
+		Horizon:  time.Hour * 2000000,
+		Policy: func(int, Clock) (xbo.BackOff, error) {
+			return xbo.NewExponential(time.Hour, 100)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.Pending != 2 {
+		t.Errorf("expected 2: %d", result.Pending)
+	}
+}
+
+func TestRunVirtualClock(t *testing.T) {
+	// The clock handed to the policy moves along with the simulation
+	var seen []time.Duration
+	_, err := Run(Config{
+		Clients:   1,
+		Capacity:  1,
+		Bucket:    time.Second,
+		OutageEnd: time.Second * 3,
+		Arrival:   time.Second,
+		Seed:      7,
+		Horizon:   time.Minute,
+		Policy: func(client int, now Clock) (xbo.BackOff, error) {
+			return xbo.BackOffFunc(func(reset bool) (time.Duration, error) {
+				seen = append(seen, now())
+				return time.Second, nil
+			}), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3: %v", seen)
+	}
+	for ix := 1; ix < len(seen); ix++ {
+		if diff := seen[ix] - seen[ix-1]; diff != time.Second {
+			t.Errorf("expected %v: %v", time.Second, diff)
+		}
+	}
+	if seen[0] <= 0 || seen[0] >= time.Second {
+		t.Errorf("expected arrival within the first second: %v", seen[0])
+	}
+}
+
+func TestRunJitterSpreadsLoad(t *testing.T) {
+	// The whole point: jitter should flatten out the peak retry load.
+	// (Everyone arrives in the first bucket, so that's not a retry.)
+	peak := func(p Factory) int {
+		result, err := Run(Config{
+			Clients:   1000,
+			Capacity:  100,
+			Bucket:    time.Second,
+			OutageEnd: time.Second * 30,
+			Horizon:   time.Minute * 10,
+			Policy:    p,
+		})
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		max := 0
+		for _, b := range result.Buckets[1:] {
+			if b.Attempts > max {
+				max = b.Attempts
+			}
+		}
+		return max
+	}
+
+	bare := peak(constant(time.Second * 5))
+	jittered := peak(func(int, Clock) (xbo.BackOff, error) {
+		return xbo.NewJitter(xbo.NewConstant(time.Second*5),
+			xbo.JitterUnder(50), xbo.JitterOver(50))
+	})
+	if jittered >= bare {
+		t.Errorf("expected jitter to reduce peak load: %d vs %d", jittered, bare)
+	}
+}
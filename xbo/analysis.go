@@ -0,0 +1,247 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"time"
+)
+
+// AnalysisLimit is the most attempts that Bounds will walk through before
+// deciding that a BackOff is unbounded.
+const AnalysisLimit = 1 << 20
+
+// UnboundedAttempts is reported by Bounds when a BackOff may make more
+// attempts than AnalysisLimit (i.e. it may never stop).
+const UnboundedAttempts = uint64(math.MaxUint64)
+
+// UnboundedDelay is reported by Bounds when a BackOff may keep the consumer
+// waiting indefinitely.
+const UnboundedDelay = time.Duration(math.MaxInt64)
+
+// Analysis describes how many attempts a BackOff allows before it says to
+// stop, and how much time in total it has the consumer wait, starting from
+// a reset. The time taken by the attempts themselves is not included.
+type Analysis struct {
+	MinAttempts      uint64
+	MaxAttempts      uint64
+	ExpectedAttempts uint64
+
+	MinDelay      time.Duration
+	MaxDelay      time.Duration
+	ExpectedDelay time.Duration
+}
+
+// Bounds analyzes a BackOff built from the generators and decorators in
+// this package, by walking through a deterministic stand-in for it in
+// virtual time: once with all randomness at the low end of its range, once
+// at the high end, and once in the middle (or at its mean). The expected
+// figures come from that last walk, so are an approximation when
+// randomness interacts with other decorators (e.g. Elapsed).
+//
+// Elapsed is the exception to walking the high end: a mix of shorter and
+// longer delays can have an attempt land right on its bound, after which
+// one more delay is allowed. So for MaxDelay, Elapsed is taken to allow up
+// to its bound, plus the longest single delay that what it decorates can
+// give.
+//
+// Generators: NewConstant, NewZero, NewStop, NewExponential, NewLoop,
+// NewLimit, NewEcho. Decorators and combinators: NewJitter, MaxAttempts,
+// Ceiling, Floor, Clamp, Elapsed (and their New... constructors), Concat,
+// Max, Min, Sum, Scale, Offset, SpreadBy, Spread, Record, ResetAfter,
+// RequireSuccesses, Hinted (which is analyzed without any hints).
+//
+// An error is returned if the BackOff (or anything it decorates) is not
+// one of those, or if the BackOff returns an error other than ErrStop.
+func Bounds(bo BackOff) (Analysis, error) {
+	var result Analysis
+	var walks [3]walk
+	for ix, e := range []estimate{estimateLow, estimateHigh, estimateMean} {
+		w, err := walkShadow(bo, e)
+		if err != nil {
+			return result, err
+		}
+		walks[ix] = w
+	}
+
+	result.MinAttempts, result.MaxAttempts = walks[0].attempts, walks[0].attempts
+	result.MinDelay, result.MaxDelay = walks[0].delay, walks[0].delay
+	for _, w := range walks[1:] {
+		if w.attempts < result.MinAttempts {
+			result.MinAttempts = w.attempts
+		}
+		if w.attempts > result.MaxAttempts {
+			result.MaxAttempts = w.attempts
+		}
+		if w.delay < result.MinDelay {
+			result.MinDelay = w.delay
+		}
+		if w.delay > result.MaxDelay {
+			result.MaxDelay = w.delay
+		}
+	}
+	result.ExpectedAttempts = walks[2].attempts
+	result.ExpectedDelay = walks[2].delay
+	return result, nil
+}
+
+type walk struct {
+	attempts uint64
+	delay    time.Duration
+	longest  time.Duration
+}
+
+func walkShadow(bo BackOff, e estimate) (walk, error) {
+	var result walk
+	var now time.Duration
+	shadow, err := shadowOf(bo, e, func() time.Duration { return now })
+	if err != nil {
+		return result, err
+	}
+
+	// Start the way a consumer would, with a reset, which usually (but
+	// not always) has no delay
+	dur, err := shadow.Next(true)
+	if err != nil {
+		return result, err
+	}
+	now = addDurations(now, dur)
+
+	for result.attempts < AnalysisLimit {
+		dur, err := shadow.Next(false)
+		if errors.Is(err, ErrStop) {
+			result.delay = now
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		result.attempts++
+		now = addDurations(now, dur)
+		if dur > result.longest {
+			result.longest = dur
+		}
+	}
+
+	result.attempts = UnboundedAttempts
+	result.delay = ZeroDuration
+	if now > 0 {
+		result.delay = UnboundedDelay
+	}
+	return result, nil
+}
+
+// estimate decides which part of the range of any randomness a shadow
+// will use
+type estimate int
+
+const (
+	estimateLow estimate = iota
+	estimateHigh
+	estimateMean
+)
+
+// Int63n makes the estimate into a (very predictable) JitterRand
+func (e estimate) Int63n(n int64) int64 {
+	switch e {
+	case estimateLow:
+		return 0
+	case estimateHigh:
+		return n - 1
+	}
+	return n / 2
+}
+
+// sinceFunc reports how much time has passed since some fixed origin
+type sinceFunc func() time.Duration
+
+// analyzer is implemented by the BackOffs that Bounds knows how to walk
+type analyzer interface {
+	// shadow creates a fresh, deterministic stand-in for the BackOff,
+	// which resolves randomness with the estimate, and tells time with
+	// the sinceFunc
+	shadow(e estimate, now sinceFunc) (BackOff, error)
+}
+
+func shadowOf(bo BackOff, e estimate, now sinceFunc) (BackOff, error) {
+	a, ok := bo.(analyzer)
+	if !ok {
+		return nil, fmt.Errorf("cannot analyze %T", bo)
+	}
+	return a.shadow(e, now)
+}
+
+func shadowsOf(bos []BackOff, e estimate, now sinceFunc) ([]BackOff, error) {
+	var result []BackOff
+	for _, bo := range bos {
+		shadow, err := shadowOf(bo, e, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, shadow)
+	}
+	return result, nil
+}
+
+// analyzable pairs one of our BackOffFunc-based BackOffs with the means
+// of shadowing it
+type analyzable struct {
+	BackOffFunc
+	shadowFunc func(e estimate, now sinceFunc) (BackOff, error)
+}
+
+func (a analyzable) shadow(e estimate, now sinceFunc) (BackOff, error) {
+	return a.shadowFunc(e, now)
+}
+
+// stateless is for BackOffs that are their own (deterministic) shadow
+func stateless(f BackOffFunc) BackOff {
+	return analyzable{f, func(estimate, sinceFunc) (BackOff, error) {
+		return f, nil
+	}}
+}
+
+// decorating is for BackOffs whose only randomness or timing comes from
+// what they decorate, so their shadow can be made by applying the same
+// decoration to the shadows of what they decorate
+func decorating(f BackOffFunc, bos []BackOff, build func([]BackOff) BackOff) analyzable {
+	return analyzable{f, func(e estimate, now sinceFunc) (BackOff, error) {
+		shadows, err := shadowsOf(bos, e, now)
+		if err != nil {
+			return nil, err
+		}
+		return build(shadows), nil
+	}}
+}
+
+// meanPosition estimates where a Distribution lands on average
+func meanPosition(d Distribution) float64 {
+	r := mrand.New(mrand.NewSource(1))
+	sum := 0.0
+	samples := 1000
+	for ix := 0; ix < samples; ix++ {
+		sum += clipPosition(d(r))
+	}
+	return sum / float64(samples)
+}
@@ -0,0 +1,220 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoundsExponential(t *testing.T) {
+	bo, err := NewExponential(time.Millisecond*100, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo = MaxAttempts(bo, 5, false)
+
+	result, err := Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	expected := Analysis{
+		MinAttempts:      5,
+		MaxAttempts:      5,
+		ExpectedAttempts: 5,
+		MinDelay:         time.Millisecond * 3100,
+		MaxDelay:         time.Millisecond * 3100,
+		ExpectedDelay:    time.Millisecond * 3100,
+	}
+	if result != expected {
+		t.Errorf("expected %+v: %+v", expected, result)
+	}
+
+	// The analysis doesn't disturb the BackOff itself
+	dur, err := bo.Next(false)
+	if dur != time.Millisecond*100 {
+		t.Errorf("expected %v: %v", time.Millisecond*100, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
+
+func TestBoundsJitter(t *testing.T) {
+	bo, err := NewExponential(time.Millisecond*100, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo, err = NewJitter(MaxAttempts(bo, 5, false), JitterUnder(50), JitterOver(50))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	result, err := Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	expected := Analysis{
+		MinAttempts:      5,
+		MaxAttempts:      5,
+		ExpectedAttempts: 5,
+		MinDelay:         time.Millisecond * 1550,
+		MaxDelay:         time.Millisecond * 4650,
+		ExpectedDelay:    time.Millisecond * 3100,
+	}
+	if result != expected {
+		t.Errorf("expected %+v: %+v", expected, result)
+	}
+
+	// Shaped jitter goes to the ends of the range, too
+	bo, err = NewJitter(NewLimit([]time.Duration{time.Second}, false),
+		JitterUnder(50), JitterOver(50), JitterGaussian(0.1))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	result, err = Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.MinDelay != time.Millisecond*500 {
+		t.Errorf("expected %v: %v", time.Millisecond*500, result.MinDelay)
+	}
+	if result.MaxDelay != time.Millisecond*1500 {
+		t.Errorf("expected %v: %v", time.Millisecond*1500, result.MaxDelay)
+	}
+	if result.ExpectedDelay < time.Millisecond*950 || result.ExpectedDelay > time.Millisecond*1050 {
+		t.Errorf("expected about %v: %v", time.Second, result.ExpectedDelay)
+	}
+}
+
+func TestBoundsElapsed(t *testing.T) {
+	// Elapsed is walked in virtual time, not real time
+	bo := Elapsed(NewConstant(time.Second), time.Second*10)
+
+	result, err := Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.MaxAttempts != 11 {
+		t.Errorf("expected %d: %d", 11, result.MaxAttempts)
+	}
+	if result.MaxDelay != time.Second*11 {
+		t.Errorf("expected %v: %v", time.Second*11, result.MaxDelay)
+	}
+}
+
+func TestBoundsElapsedJitter(t *testing.T) {
+	// A mix of short and long delays can land an attempt right on the
+	// bound (e.g. 1s, 3s, 3s, 3s), leaving room for one more long delay
+	bo, err := NewJitter(NewConstant(time.Second*2), JitterUnder(50), JitterOver(50))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo = Elapsed(bo, time.Second*10)
+
+	result, err := Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.MaxDelay != time.Second*13 {
+		t.Errorf("expected %v: %v", time.Second*13, result.MaxDelay)
+	}
+
+	// Which is a wait that can really happen
+	var now time.Duration
+	clock := func() time.Time { return time.Unix(0, 0).Add(now) }
+	echo := NewEcho([]time.Duration{time.Second, time.Second * 3}, false)
+	real, err := NewElapsed(echo, time.Second*10, BoundClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	for {
+		dur, err := real.Next(false)
+		if err != nil {
+			break
+		}
+		now += dur
+	}
+	if now != result.MaxDelay {
+		t.Errorf("expected %v: %v", result.MaxDelay, now)
+	}
+}
+
+func TestBoundsConcat(t *testing.T) {
+	bo := Concat(false,
+		NewLimit([]time.Duration{time.Second, time.Second * 2}, false),
+		MaxAttempts(NewConstant(time.Second*5), 2, false),
+	)
+
+	result, err := Bounds(bo)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if result.MaxAttempts != 4 {
+		t.Errorf("expected %d: %d", 4, result.MaxAttempts)
+	}
+	if result.MaxDelay != time.Second*13 {
+		t.Errorf("expected %v: %v", time.Second*13, result.MaxDelay)
+	}
+}
+
+func TestBoundsUnbounded(t *testing.T) {
+	testCases := []struct {
+		bo    BackOff
+		delay time.Duration
+	}{
+		{NewLoop([]time.Duration{time.Second}, false), UnboundedDelay},
+		{NewZero(), ZeroDuration},
+		{Ceiling(NewConstant(time.Second), time.Minute), UnboundedDelay},
+	}
+
+	for ix, tc := range testCases {
+		result, err := Bounds(tc.bo)
+		if err != nil {
+			t.Errorf("%d: unexpected: %v", ix, err)
+		}
+		if result.MaxAttempts != UnboundedAttempts {
+			t.Errorf("%d: expected unbounded: %d", ix, result.MaxAttempts)
+		}
+		if result.MaxDelay != tc.delay {
+			t.Errorf("%d: expected %v: %v", ix, tc.delay, result.MaxDelay)
+		}
+	}
+}
+
+func TestBoundsErrors(t *testing.T) {
+	custom := BackOffFunc(func(reset bool) (time.Duration, error) {
+		return time.Second, nil
+	})
+
+	testCases := []BackOff{
+		custom,
+		MaxAttempts(custom, 3, false),
+		MaxAttempts(NewConstant(time.Second), 0, false),
+	}
+
+	for ix, bo := range testCases {
+		_, err := Bounds(bo)
+		if err == nil {
+			t.Errorf("%d: expected error", ix)
+		}
+	}
+}
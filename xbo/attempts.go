@@ -0,0 +1,81 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// Attempt is handed out by the iterators from Attempts, once it is time to
+// make the attempt.
+type Attempt struct {
+	// Number counts up from 1
+	Number int
+	// Delay is how long was waited before this attempt (for the first
+	// attempt, that is whatever the BackOff said on being reset)
+	Delay time.Duration
+}
+
+// Attempts gives an iterator that resets the BackOff, and then yields an
+// Attempt each time the consumer should try again, waiting between them as
+// a Waiter would. Break out of the loop once an attempt succeeds:
+//
+//	for attempt, err := range xbo.Attempts(ctx, bo) {
+//		if err != nil {
+//			return err
+//		}
+//		if err = try(ctx); err == nil {
+//			break
+//		}
+//	}
+//
+// The iteration ends when the BackOff says to stop, returns any other
+// error, or the Context is done. Whichever it was is yielded (with the
+// Number the next attempt would have had) as the final iteration, so a
+// non-nil error always means there is no attempt to make.
+func Attempts(ctx context.Context, bo BackOff) iter.Seq2[Attempt, error] {
+	return Waiter{bo: bo}.Attempts(ctx)
+}
+
+// Attempts is like the package-level Attempts function, but waits according
+// to the options of this Waiter (e.g. WaiterDeadline).
+func (w Waiter) Attempts(ctx context.Context) iter.Seq2[Attempt, error] {
+	return func(yield func(Attempt, error) bool) {
+		reset := true
+		for number := 1; ; number++ {
+			delay, err := w.wait(ctx, reset)
+			if err == nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				yield(Attempt{Number: number}, err)
+				return
+			}
+			reset = false
+
+			if !yield(Attempt{Number: number, Delay: delay}, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+)
+
+// collect gathers up the attempts, along with the error that ended them
+func collect(t *testing.T, seq iter.Seq2[Attempt, error]) ([]Attempt, error) {
+	t.Helper()
+	var attempts []Attempt
+	var final error
+	for attempt, err := range seq {
+		if final != nil {
+			t.Errorf("unexpected iteration after %v: %+v", final, attempt)
+		}
+		if err != nil {
+			if attempt.Number != len(attempts)+1 {
+				t.Errorf("expected %d: %d", len(attempts)+1, attempt.Number)
+			}
+			final = err
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, final
+}
+
+func TestAttempts(t *testing.T) {
+	ms := time.Millisecond
+	bo := NewLimit([]time.Duration{ms, ms * 2, ms * 3}, false)
+
+	expected := []Attempt{
+		{Number: 1, Delay: ZeroDuration},
+		{Number: 2, Delay: ms},
+		{Number: 3, Delay: ms * 2},
+		{Number: 4, Delay: ms * 3},
+	}
+
+	// Several cycles, to prove each iteration starts with a reset
+	for cycle := 0; cycle < 2; cycle++ {
+		start := time.Now()
+		attempts, err := collect(t, Attempts(context.Background(), bo))
+		if !errors.Is(err, ErrStop) {
+			t.Errorf("expected %v: %v", ErrStop, err)
+		}
+		if len(attempts) != len(expected) {
+			t.Fatalf("expected %d: %d", len(expected), len(attempts))
+		}
+		for ix, attempt := range attempts {
+			if attempt != expected[ix] {
+				t.Errorf("expected %+v: %+v", expected[ix], attempt)
+			}
+		}
+		if elapsed := time.Since(start); elapsed < ms*6 {
+			t.Errorf("expected at least %v: %v", ms*6, elapsed)
+		}
+	}
+}
+
+func TestAttemptsBreak(t *testing.T) {
+	calls := 0
+	bo := BackOffFunc(func(reset bool) (time.Duration, error) {
+		calls++
+		return ZeroDuration, nil
+	})
+
+	last := 0
+	for attempt, err := range Attempts(context.Background(), bo) {
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		last = attempt.Number
+		if attempt.Number == 3 {
+			break
+		}
+	}
+	if last != 3 {
+		t.Errorf("expected %d: %d", 3, last)
+	}
+
+	// Nothing more is asked of the BackOff once we've succeeded
+	if calls != 3 {
+		t.Errorf("expected %d: %d", 3, calls)
+	}
+}
+
+func TestAttemptsMisconfigured(t *testing.T) {
+	attempts, err := collect(t, Attempts(context.Background(), Ceiling(NewZero(), 0)))
+	if len(attempts) != 0 {
+		t.Errorf("unexpected attempts: %v", attempts)
+	}
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+}
+
+func TestAttemptsContext(t *testing.T) {
+	ctx, cxl := context.WithTimeout(context.Background(), time.Millisecond*120)
+	defer cxl()
+
+	attempts, err := collect(t, Attempts(ctx, NewConstant(time.Millisecond*50)))
+	if len(attempts) != 3 {
+		t.Errorf("expected %d: %d", 3, len(attempts))
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v: %v", context.DeadlineExceeded, err)
+	}
+
+	// A Context that's already done gets no attempts at all
+	attempts, err = collect(t, Attempts(ctx, NewZero()))
+	if len(attempts) != 0 {
+		t.Errorf("unexpected attempts: %v", attempts)
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWaiterAttempts(t *testing.T) {
+	w, err := NewWaiter(NewConstant(time.Millisecond*50), WaiterDeadline(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	ctx, cxl := context.WithTimeout(context.Background(), time.Millisecond*120)
+	defer cxl()
+
+	// The deadline-aware Waiter gives up without waiting out the Context
+	start := time.Now()
+	attempts, err := collect(t, w.Attempts(ctx))
+	if len(attempts) != 3 {
+		t.Errorf("expected %d: %d", 3, len(attempts))
+	}
+	if err != ErrDeadlineTooShort {
+		t.Errorf("expected %v: %v", ErrDeadlineTooShort, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*115 {
+		t.Errorf("expected less than %v: %v", time.Millisecond*115, elapsed)
+	}
+}
@@ -32,10 +32,12 @@ var ErrLowBound = fmt.Errorf("boundary condition too low")
 
 // MaxAttempts is a BackOff decorator that will short-circuit the underlying
 // BackOff if there have been too many un-reset requests in a row.
-// This can be made concurrent-safe by setting the safe value to true
+// This can be made concurrent-safe by setting the safe value to true.
+// See NewMaxAttempts for a version that checks the bound up front.
 func MaxAttempts(bo BackOff, bound uint32, safe bool) BackOff {
 	count := uint32(0)
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	var decide func() (Decision, error)
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
 		// Check for non-sensical boundary condition
 		if bound < 1 {
 			return ZeroDuration, ErrLowBound
@@ -51,28 +53,50 @@ func MaxAttempts(bo BackOff, bound uint32, safe bool) BackOff {
 			return bo.Next(reset)
 		}
 
+		d, err := decide()
+		return d.Delay, err
+	})
+	decide = func() (Decision, error) {
+		// Check for non-sensical boundary condition
+		if bound < 1 {
+			return Decision{}, ErrLowBound
+		}
+
 		// Calculate how many sequential attempts have been made
-		next := count + 1
+		var next uint32
 		if safe {
 			next = atomic.AddUint32(&count, 1)
 		} else {
-			count = next
+			count++
+			next = count
 		}
+		result := Decision{Attempt: uint64(next)}
 
 		// We've maxed out the attempts, tell them to stop
 		if next > bound {
-			return ZeroDuration, ErrStop
+			result.Reason = ReasonAttempts
+			return result, &StopError{
+				Reason:   ReasonAttempts,
+				Source:   "MaxAttempts",
+				Attempts: uint64(bound),
+			}
 		}
 
 		// Fall back to the underlying BackOff
-		return bo.Next(reset)
-	})
+		var err error
+		result.Delay, err = bo.Next(false)
+		return result, err
+	}
+	return counting{decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return MaxAttempts(shadows[0], bound, false)
+	}), decide}
 }
 
 // Ceiling is a BackOff decorator that limits the maximum duration the consumer
-// will be told to wait.
+// will be told to wait. See NewCeiling for a version that checks the bound
+// up front.
 func Ceiling(bo BackOff, bound time.Duration) BackOff {
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
 		// Check for non-sensical boundary condition
 		if bound < 1 {
 			return ZeroDuration, ErrLowBound
@@ -89,14 +113,75 @@ func Ceiling(bo BackOff, bound time.Duration) BackOff {
 		// Otherwise we let the underlying BackOff stand
 		return dur, err
 	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return Ceiling(shadows[0], bound)
+	})
+}
+
+// Floor is a BackOff decorator that limits the minimum duration the consumer
+// will be told to wait. See NewFloor for a version that checks the bound
+// up front.
+func Floor(bo BackOff, bound time.Duration) BackOff {
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Check for non-sensical boundary condition
+		if bound < 1 {
+			return ZeroDuration, ErrLowBound
+		}
+
+		// Find out what the underlying BackOff says
+		dur, err := bo.Next(reset)
+
+		// We only interject for non-reset, non-error conditions
+		if err == nil && !reset && dur < bound {
+			return bound, nil
+		}
+
+		// Otherwise we let the underlying BackOff stand
+		return dur, err
+	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return Floor(shadows[0], bound)
+	})
+}
+
+// Clamp is a BackOff decorator that keeps the durations the consumer will be
+// told to wait between the min and max bounds (inclusive). Unlike Floor and
+// Ceiling, the bounds are checked up front, and an error is returned if
+// they are non-sensical or out of order.
+func Clamp(bo BackOff, min time.Duration, max time.Duration) (BackOff, error) {
+	if bo == nil {
+		return nil, fmt.Errorf("backoff source is required")
+	}
+	if min < 1 {
+		return nil, ErrLowBound
+	}
+	if max < min {
+		return nil, fmt.Errorf("max must not be less than min: %v < %v", max, min)
+	}
+	return Ceiling(Floor(bo, min), max), nil
 }
 
 // Elapsed is a BackOff decorator that will short-circuit the underlying
 // BackOff if too much time has elapsed since the last reset, and will
-// return ErrStop if that is the case.
+// return a StopError if that is the case.
+// The start time is tracked atomically, so this is always concurrent-safe.
+// See NewElapsed for a version that checks the bound up front.
 func Elapsed(bo BackOff, bound time.Duration) BackOff {
-	start := time.Now()
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	origin := time.Now()
+	return elapsed(bo, bound, func() time.Duration {
+		return time.Since(origin)
+	})
+}
+
+// elapsed does the work of Elapsed, telling the time with the sinceFunc.
+// The start is kept as an offset from the fixed origin of the sinceFunc,
+// so that it can be stored atomically without losing the monotonic clock
+// reading.
+func elapsed(bo BackOff, bound time.Duration, since sinceFunc) BackOff {
+	start := int64(since())
+	var attempts uint64
+	var decide func() (Decision, error)
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
 		// Check for non-sensical boundary condition
 		if bound < 1 {
 			return ZeroDuration, ErrLowBound
@@ -104,16 +189,203 @@ func Elapsed(bo BackOff, bound time.Duration) BackOff {
 
 		// Restart the clock on reset
 		if reset {
-			start = time.Now()
+			atomic.StoreInt64(&start, int64(since()))
+			atomic.StoreUint64(&attempts, 0)
 			return bo.Next(reset)
 		}
 
+		d, err := decide()
+		return d.Delay, err
+	})
+	decide = func() (Decision, error) {
+		// Check for non-sensical boundary condition
+		if bound < 1 {
+			return Decision{}, ErrLowBound
+		}
+
 		// Check elapsed before delegating, for short-circuit
-		if time.Now().Sub(start) > bound {
-			return ZeroDuration, ErrStop
+		passed := since() - time.Duration(atomic.LoadInt64(&start))
+		if passed > bound {
+			allowed := atomic.LoadUint64(&attempts)
+			return Decision{Attempt: allowed + 1, Reason: ReasonElapsed}, &StopError{
+				Reason:   ReasonElapsed,
+				Source:   "Elapsed",
+				Attempts: allowed,
+				Elapsed:  passed,
+			}
 		}
 
 		// Fall back to the underlying BackOff
-		return bo.Next(reset)
+		result := Decision{Attempt: atomic.AddUint64(&attempts, 1)}
+		var err error
+		result.Delay, err = bo.Next(false)
+		return result, err
+	}
+	return counting{analyzable{f, func(e estimate, now sinceFunc) (BackOff, error) {
+		shadow, err := shadowOf(bo, e, now)
+		if err != nil {
+			return nil, err
+		}
+		if e != estimateHigh {
+			return elapsed(shadow, bound, now), nil
+		}
+
+		// Find the longest delay we might be told to wait, in a walk of
+		// its own
+		w, err := walkShadow(bo, estimateHigh)
+		if err != nil {
+			return nil, err
+		}
+		return worstElapsed(shadow, bound, w.longest, now), nil
+	}}, decide}
+}
+
+// worstElapsed stands in for Elapsed when looking for the longest total
+// wait. Any delay that would carry past the bound is cut short to land
+// right on it, and the attempt made there is followed by the longest delay
+// the underlying BackOff can give.
+func worstElapsed(bo BackOff, bound time.Duration, longest time.Duration, since sinceFunc) BackOff {
+	start := since()
+	return BackOffFunc(func(reset bool) (time.Duration, error) {
+		if bound < 1 {
+			return ZeroDuration, ErrLowBound
+		}
+		if reset {
+			start = since()
+			return bo.Next(reset)
+		}
+
+		passed := since() - start
+		if passed > bound {
+			return ZeroDuration, &StopError{
+				Reason:  ReasonElapsed,
+				Source:  "Elapsed",
+				Elapsed: passed,
+			}
+		}
+
+		// Keep the underlying BackOff in step, whatever we make of it
+		dur, err := bo.Next(false)
+		if err != nil {
+			return dur, err
+		}
+		switch {
+		case passed == bound:
+			if longest > dur {
+				dur = longest
+			}
+		case dur > bound-passed:
+			dur = bound - passed
+		}
+		return dur, nil
 	})
 }
+
+// BoundOption declares the functional options for changing behavior on the
+// BackOffs created by NewMaxAttempts, NewCeiling, NewFloor, NewElapsed and
+// ResetAfter.
+type BoundOption func(*bounded) error
+
+type bounded struct {
+	safe bool
+	now  func() time.Time
+}
+
+// BoundSafe is used to make sure any internal state of the created BackOff
+// is updated in an atomic and concurrent-safe manner. It has no effect on
+// decorators that don't keep state of their own (Ceiling and Floor), nor on
+// Elapsed, which is always concurrent-safe.
+func BoundSafe(safe bool) BoundOption {
+	return BoundOption(func(b *bounded) error {
+		b.safe = safe
+		return nil
+	})
+}
+
+// BoundClock gives the consumer the option of specifying how the created
+// BackOff tells the time, which is mostly useful for testing. The default
+// is time.Now.
+func BoundClock(now func() time.Time) BoundOption {
+	return BoundOption(func(b *bounded) error {
+		if now == nil {
+			return fmt.Errorf("nil clock")
+		}
+		b.now = now
+		return nil
+	})
+}
+
+func newBounded(bo BackOff, options []BoundOption) (*bounded, error) {
+	if bo == nil {
+		return nil, fmt.Errorf("backoff source is required")
+	}
+
+	result := &bounded{now: time.Now}
+	for _, opt := range options {
+		err := opt(result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// NewMaxAttempts is like MaxAttempts, except that the bound is checked up
+// front, and an error is returned if it is non-sensical (or if there is no
+// underlying BackOff).
+func NewMaxAttempts(bo BackOff, bound uint32, options ...BoundOption) (BackOff, error) {
+	b, err := newBounded(bo, options)
+	if err != nil {
+		return nil, err
+	}
+	if bound < 1 {
+		return nil, ErrLowBound
+	}
+	return MaxAttempts(bo, bound, b.safe), nil
+}
+
+// NewCeiling is like Ceiling, except that the bound is checked up front, and
+// an error is returned if it is non-sensical (or if there is no underlying
+// BackOff).
+func NewCeiling(bo BackOff, bound time.Duration, options ...BoundOption) (BackOff, error) {
+	_, err := newBounded(bo, options)
+	if err != nil {
+		return nil, err
+	}
+	if bound < 1 {
+		return nil, ErrLowBound
+	}
+	return Ceiling(bo, bound), nil
+}
+
+// NewFloor is like Floor, except that the bound is checked up front, and an
+// error is returned if it is non-sensical (or if there is no underlying
+// BackOff).
+func NewFloor(bo BackOff, bound time.Duration, options ...BoundOption) (BackOff, error) {
+	_, err := newBounded(bo, options)
+	if err != nil {
+		return nil, err
+	}
+	if bound < 1 {
+		return nil, ErrLowBound
+	}
+	return Floor(bo, bound), nil
+}
+
+// NewElapsed is like Elapsed, except that the bound is checked up front, and
+// an error is returned if it is non-sensical (or if there is no underlying
+// BackOff). Use BoundClock to control how the time is told.
+func NewElapsed(bo BackOff, bound time.Duration, options ...BoundOption) (BackOff, error) {
+	b, err := newBounded(bo, options)
+	if err != nil {
+		return nil, err
+	}
+	if bound < 1 {
+		return nil, ErrLowBound
+	}
+	now := b.now
+	origin := now()
+	return elapsed(bo, bound, func() time.Duration {
+		return now().Sub(origin)
+	}), nil
+}
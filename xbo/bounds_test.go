@@ -21,6 +21,7 @@
 package xbo
 
 import (
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
@@ -32,6 +33,7 @@ func TestBoundaryErrors(t *testing.T) {
 		MaxAttempts(NewStop(), 0, false),
 		MaxAttempts(NewStop(), 0, true),
 		Ceiling(NewStop(), 0),
+		Floor(NewStop(), 0),
 		Elapsed(NewStop(), 0),
 	}
 	resets := []bool{true, false}
@@ -78,7 +80,7 @@ func TestMaxAttempts(t *testing.T) {
 				if dur != ZeroDuration {
 					t.Errorf("expected %v: %v", ZeroDuration, dur)
 				}
-				if err != ErrStop {
+				if !errors.Is(err, ErrStop) {
 					t.Errorf("expected %v: %v", ErrStop, err)
 				}
 			}
@@ -180,7 +182,7 @@ func TestElapsed(t *testing.T) {
 			if dur != ZeroDuration {
 				t.Errorf("expected %v: %v", ZeroDuration, dur)
 			}
-			if err != ErrStop {
+			if !errors.Is(err, ErrStop) {
 				t.Errorf("expected %v: %v", ErrStop, err)
 			}
 		}
@@ -227,7 +229,7 @@ func TestMaxAttemptsShortCircuit(t *testing.T) {
 		if dur != ZeroDuration {
 			t.Errorf("expected %v: %v", ZeroDuration, dur)
 		}
-		if err != ErrStop {
+		if !errors.Is(err, ErrStop) {
 			t.Errorf("expected %v: %v", ErrStop, err)
 		}
 	}
@@ -269,7 +271,7 @@ func TestElapsedShortCircuit(t *testing.T) {
 		if dur != ZeroDuration {
 			t.Errorf("expected %v: %v", ZeroDuration, dur)
 		}
-		if err != ErrStop {
+		if !errors.Is(err, ErrStop) {
 			t.Errorf("expected %v: %v", ErrStop, err)
 		}
 	}
@@ -286,5 +288,238 @@ func TestElapsedShortCircuit(t *testing.T) {
 	}
 }
 
-// TODO: more tests
-// TestMaxAttemptsSafe
+func TestFloor(t *testing.T) {
+	under := NewLimit([]time.Duration{
+		time.Millisecond, time.Millisecond * 20, time.Millisecond * 5,
+	}, false)
+
+	bottom := time.Millisecond * 10
+	bo := Floor(under, bottom)
+	expected := []time.Duration{bottom, time.Millisecond * 20, bottom}
+
+	// Several cycles to prove reset works
+	cycles := 2 + rand.Intn(3)
+	for ix := 0; ix < cycles; ix++ {
+		for _, expect := range expected {
+			dur, err := bo.Next(false)
+			if dur != expect {
+				t.Errorf("expected %v: %v", expect, dur)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+		}
+
+		// Errors from the underlying are not raised to the floor
+		dur, err := bo.Next(false)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if !errors.Is(err, ErrStop) {
+			t.Errorf("expected %v: %v", ErrStop, err)
+		}
+
+		// Neither are resets
+		dur, err = bo.Next(true)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
+
+func TestClampErrors(t *testing.T) {
+	inputs := []struct {
+		bo  BackOff
+		min time.Duration
+		max time.Duration
+	}{
+		{nil, time.Second, time.Minute},
+		{NewZero(), 0, time.Minute},
+		{NewZero(), time.Minute, time.Second},
+	}
+	for _, input := range inputs {
+		bo, err := Clamp(input.bo, input.min, input.max)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if bo != nil {
+			t.Errorf("expected nil: %v", bo)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	under := NewLimit([]time.Duration{
+		time.Millisecond, time.Second, time.Hour,
+	}, false)
+
+	bo, err := Clamp(under, time.Millisecond*10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	expected := []time.Duration{time.Millisecond * 10, time.Second, time.Minute}
+	for _, expect := range expected {
+		dur, err := bo.Next(false)
+		if dur != expect {
+			t.Errorf("expected %v: %v", expect, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+
+	// A degenerate (but ordered) clamp is just a constant
+	bo, err = Clamp(NewLoop(expected, false), time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	for _, expect := range expected {
+		dur, err := bo.Next(false)
+		if dur != time.Second {
+			t.Errorf("expected %v for %v: %v", time.Second, expect, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
+
+func TestMaxAttemptsSafe(t *testing.T) {
+	bound := uint32(100)
+	bo := MaxAttempts(NewConstant(time.Second), bound, true)
+	got, stops := hammer(t, bo, 200, 50, 0)
+	if len(got) != int(bound) {
+		t.Errorf("expected %d: %d", bound, len(got))
+	}
+	if stops != 200*50-int(bound) {
+		t.Errorf("expected %d: %d", 200*50-int(bound), stops)
+	}
+
+	// And resets in the mix don't cause any trouble
+	hammer(t, bo, 200, 50, 3)
+}
+
+func TestElapsedSafe(t *testing.T) {
+	bo := Elapsed(NewConstant(time.Second), time.Millisecond)
+
+	// Whether or not the bound has passed yet, the only answers are the
+	// underlying duration, or to stop (hammer fails on any other error)
+	durs, stops := hammer(t, bo, 200, 50, 5)
+	for _, dur := range durs {
+		if dur != time.Second {
+			t.Fatalf("expected %v: %v", time.Second, dur)
+		}
+	}
+	if len(durs)+stops != 200*40 {
+		t.Errorf("expected %d: %d", 200*40, len(durs)+stops)
+	}
+
+	// Once the bound has passed, with no resets, every call stops
+	time.Sleep(time.Millisecond * 2)
+	durs, stops = hammer(t, bo, 200, 50, 0)
+	if len(durs) != 0 {
+		t.Errorf("unexpected durations: %d", len(durs))
+	}
+	if stops != 200*50 {
+		t.Errorf("expected %d: %d", 200*50, stops)
+	}
+}
+
+func TestBoundConstructorErrors(t *testing.T) {
+	constructors := map[string]func(BackOff, time.Duration, ...BoundOption) (BackOff, error){
+		"attempts": func(bo BackOff, bound time.Duration, options ...BoundOption) (BackOff, error) {
+			return NewMaxAttempts(bo, uint32(bound), options...)
+		},
+		"ceiling": NewCeiling,
+		"floor":   NewFloor,
+		"elapsed": NewElapsed,
+	}
+	inputs := []struct {
+		bo      BackOff
+		bound   time.Duration
+		options []BoundOption
+	}{
+		{nil, time.Second, nil},
+		{NewZero(), 0, nil},
+		{NewZero(), time.Second, []BoundOption{BoundClock(nil)}},
+	}
+	for name, constructor := range constructors {
+		for ix, input := range inputs {
+			bo, err := constructor(input.bo, input.bound, input.options...)
+			if err == nil {
+				t.Errorf("%s %d: expected error", name, ix)
+			}
+			if bo != nil {
+				t.Errorf("%s %d: expected nil: %v", name, ix, bo)
+			}
+		}
+
+		// But sensible inputs are fine
+		bo, err := constructor(NewZero(), time.Second, BoundSafe(true))
+		if err != nil {
+			t.Errorf("%s: unexpected: %v", name, err)
+		}
+		if bo == nil {
+			t.Errorf("%s: expected BackOff", name)
+		}
+	}
+}
+
+func TestNewMaxAttempts(t *testing.T) {
+	bo, err := NewMaxAttempts(NewConstant(time.Second), 2, BoundSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	for ix := 0; ix < 2; ix++ {
+		dur, err := bo.Next(false)
+		if dur != time.Second {
+			t.Errorf("expected %v: %v", time.Second, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+	_, err = bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+}
+
+func TestNewElapsed(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	bo, err := NewElapsed(NewConstant(time.Second), time.Minute, BoundClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	// Still within the window
+	now = now.Add(time.Minute)
+	dur, err := bo.Next(false)
+	if dur != time.Second {
+		t.Errorf("expected %v: %v", time.Second, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+
+	// Over time
+	now = now.Add(time.Second)
+	_, err = bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	// Reset starts the clock again
+	bo.Next(true)
+	now = now.Add(time.Minute)
+	_, err = bo.Next(false)
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// StopMode declares how combinators that consult several BackOffs at once
+// react when some of them return ErrStop.
+type StopMode int
+
+const (
+	// StopOnAny means the combination stops as soon as any of the
+	// underlying BackOffs says to stop.
+	StopOnAny StopMode = iota
+
+	// StopOnAll means the combination only stops once all of the
+	// underlying BackOffs say to stop. Until then, the ones that have
+	// stopped are left out of the calculation.
+	StopOnAll
+)
+
+// Concat creates a BackOff that drains each of the given BackOffs in turn.
+// Once a BackOff returns ErrStop, the next one in the list takes over, until
+// they have all been exhausted, at which time ErrStop is returned. Resets
+// are sent to every phase, and start the iteration over at the first one.
+// This can be made concurrent-safe by setting the safe value to true.
+//
+// Misconfiguration (no BackOffs, or a nil BackOff) will create a BackOff
+// that always returns ErrStop (when not being reset).
+func Concat(safe bool, bos ...BackOff) BackOff {
+	if len(bos) == 0 {
+		return NewStop()
+	}
+	for _, bo := range bos {
+		if bo == nil {
+			return NewStop()
+		}
+	}
+
+	var mu sync.Mutex
+	phase := 0
+	var stop error
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		if safe {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+
+		// Every phase needs to hear about the reset, not just
+		// the one that we are currently on
+		if reset {
+			phase = 0
+			stop = nil
+			var result error
+			for _, bo := range bos {
+				_, err := bo.Next(reset)
+				if err != nil && result == nil {
+					result = err
+				}
+			}
+			return ZeroDuration, result
+		}
+
+		for phase < len(bos) {
+			dur, err := bos[phase].Next(reset)
+			if errors.Is(err, ErrStop) {
+				// This phase is drained, move along to the next
+				phase++
+				stop = err
+				continue
+			}
+			return dur, err
+		}
+		return ZeroDuration, stopped(stop, "Concat")
+	})
+	return decorating(f, bos, func(shadows []BackOff) BackOff {
+		return Concat(false, shadows...)
+	})
+}
+
+// Max creates a BackOff that consults all of the given BackOffs in lockstep,
+// and returns the largest of their durations. Resets are sent to all of them.
+//
+// Misconfiguration (no BackOffs, or a nil BackOff) will create a BackOff
+// that always returns ErrStop (when not being reset).
+func Max(mode StopMode, bos ...BackOff) BackOff {
+	return combine("Max", mode, bos, func(a, b time.Duration) time.Duration {
+		if b > a {
+			return b
+		}
+		return a
+	})
+}
+
+// Min creates a BackOff that consults all of the given BackOffs in lockstep,
+// and returns the smallest of their durations. Resets are sent to all of them.
+//
+// Misconfiguration (no BackOffs, or a nil BackOff) will create a BackOff
+// that always returns ErrStop (when not being reset).
+func Min(mode StopMode, bos ...BackOff) BackOff {
+	return combine("Min", mode, bos, func(a, b time.Duration) time.Duration {
+		if b < a {
+			return b
+		}
+		return a
+	})
+}
+
+// Sum creates a BackOff that consults all of the given BackOffs in lockstep,
+// and returns the total of their durations. Resets are sent to all of them.
+//
+// Misconfiguration (no BackOffs, or a nil BackOff) will create a BackOff
+// that always returns ErrStop (when not being reset).
+func Sum(mode StopMode, bos ...BackOff) BackOff {
+	return combine("Sum", mode, bos, addDurations)
+}
+
+func combine(name string, mode StopMode, bos []BackOff, fold func(a, b time.Duration) time.Duration) BackOff {
+	if len(bos) == 0 {
+		return NewStop()
+	}
+	for _, bo := range bos {
+		if bo == nil {
+			return NewStop()
+		}
+	}
+
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Everyone gets asked every time, so they all stay in lockstep,
+		// even if we already know what the answer is going to be
+		var result time.Duration
+		var failure, stop error
+		stops, counted := 0, 0
+		for _, bo := range bos {
+			dur, err := bo.Next(reset)
+			if errors.Is(err, ErrStop) {
+				if stops == 0 {
+					stop = err
+				}
+				stops++
+				continue
+			}
+			if err != nil {
+				if failure == nil {
+					failure = err
+				}
+				continue
+			}
+			if counted == 0 {
+				result = dur
+			} else {
+				result = fold(result, dur)
+			}
+			counted++
+		}
+
+		if failure != nil {
+			return ZeroDuration, failure
+		}
+		if reset {
+			return ZeroDuration, nil
+		}
+		if stops == len(bos) || (stops > 0 && mode == StopOnAny) {
+			return ZeroDuration, stopped(stop, name)
+		}
+		return result, nil
+	})
+	return decorating(f, bos, func(shadows []BackOff) BackOff {
+		return combine(name, mode, shadows, fold)
+	})
+}
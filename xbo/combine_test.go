@@ -0,0 +1,222 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConcatMisconfigured(t *testing.T) {
+	offs := []BackOff{
+		Concat(false),
+		Concat(true),
+		Concat(false, NewConstant(time.Second), nil),
+	}
+
+	for ct, off := range offs {
+		dur, err := off.Next(false)
+		if dur != ZeroDuration {
+			t.Errorf("%d expected %s: %s", ct, ZeroDuration, dur)
+		}
+		if !errors.Is(err, ErrStop) {
+			t.Errorf("%d expected %v: %v", ct, ErrStop, err)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	ms := time.Millisecond
+	expected := []time.Duration{
+		50 * ms, 50 * ms, 50 * ms,
+		time.Second, 2 * time.Second, 4 * time.Second,
+	}
+
+	safes := []bool{true, false}
+	for _, safe := range safes {
+		fast := NewLimit([]time.Duration{50 * ms, 50 * ms, 50 * ms}, safe)
+		slow, err := NewExponential(time.Second, 1.0, ExponentialSafe(safe))
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		bo := Concat(safe, fast, MaxAttempts(slow, 3, safe))
+
+		// Several cycles to prove reset reaches every phase
+		cycles := 3
+		for ix := 0; ix < cycles; ix++ {
+			for _, expect := range expected {
+				dur, err := bo.Next(false)
+				if dur != expect {
+					t.Errorf("expected %s: %s", expect, dur)
+				}
+				if err != nil {
+					t.Errorf("unexpected: %v", err)
+				}
+			}
+
+			// All phases are drained now
+			for jx := 0; jx < 3; jx++ {
+				dur, err := bo.Next(false)
+				if dur != ZeroDuration {
+					t.Errorf("expected %s: %s", ZeroDuration, dur)
+				}
+				if !errors.Is(err, ErrStop) {
+					t.Errorf("expected %v: %v", ErrStop, err)
+				}
+			}
+
+			dur, err := bo.Next(true)
+			if dur != ZeroDuration {
+				t.Errorf("expected %s: %s", ZeroDuration, dur)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+		}
+	}
+}
+
+func TestConcatErrors(t *testing.T) {
+	// Errors other than ErrStop do not advance to the next phase
+	bo := Concat(false, Ceiling(NewZero(), 0), NewConstant(time.Second))
+	for ix := 0; ix < 3; ix++ {
+		dur, err := bo.Next(false)
+		if dur != ZeroDuration {
+			t.Errorf("expected %s: %s", ZeroDuration, dur)
+		}
+		if err != ErrLowBound {
+			t.Errorf("expected %v: %v", ErrLowBound, err)
+		}
+	}
+
+	// And they surface through resets as well
+	_, err := bo.Next(true)
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+}
+
+func TestCombinatorsMisconfigured(t *testing.T) {
+	offs := []BackOff{
+		Max(StopOnAny),
+		Min(StopOnAll),
+		Sum(StopOnAny, NewConstant(time.Second), nil),
+	}
+
+	for ct, off := range offs {
+		dur, err := off.Next(false)
+		if dur != ZeroDuration {
+			t.Errorf("%d expected %s: %s", ct, ZeroDuration, dur)
+		}
+		if !errors.Is(err, ErrStop) {
+			t.Errorf("%d expected %v: %v", ct, ErrStop, err)
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	s := time.Second
+	short := func() BackOff {
+		return NewLimit([]time.Duration{1 * s, 5 * s}, false)
+	}
+	long := func() BackOff {
+		return NewLimit([]time.Duration{3 * s, 3 * s, 3 * s}, false)
+	}
+
+	testCases := []struct {
+		bo   BackOff
+		durs []time.Duration
+	}{
+		{Max(StopOnAny, short(), long()), []time.Duration{3 * s, 5 * s}},
+		{Max(StopOnAll, short(), long()), []time.Duration{3 * s, 5 * s, 3 * s}},
+		{Min(StopOnAny, short(), long()), []time.Duration{1 * s, 3 * s}},
+		{Min(StopOnAll, short(), long()), []time.Duration{1 * s, 3 * s, 3 * s}},
+		{Sum(StopOnAny, short(), long()), []time.Duration{4 * s, 8 * s}},
+		{Sum(StopOnAll, short(), long()), []time.Duration{4 * s, 8 * s, 3 * s}},
+		{Sum(StopOnAll, short()), []time.Duration{1 * s, 5 * s}},
+	}
+
+	for ct, tc := range testCases {
+		// Several cycles to prove resets reach everyone
+		for ix := 0; ix < 3; ix++ {
+			for _, expect := range tc.durs {
+				dur, err := tc.bo.Next(false)
+				if dur != expect {
+					t.Errorf("%d expected %s: %s", ct, expect, dur)
+				}
+				if err != nil {
+					t.Errorf("%d unexpected: %v", ct, err)
+				}
+			}
+
+			dur, err := tc.bo.Next(false)
+			if dur != ZeroDuration {
+				t.Errorf("%d expected %s: %s", ct, ZeroDuration, dur)
+			}
+			if !errors.Is(err, ErrStop) {
+				t.Errorf("%d expected %v: %v", ct, ErrStop, err)
+			}
+
+			dur, err = tc.bo.Next(true)
+			if dur != ZeroDuration {
+				t.Errorf("%d expected %s: %s", ct, ZeroDuration, dur)
+			}
+			if err != nil {
+				t.Errorf("%d unexpected: %v", ct, err)
+			}
+		}
+	}
+}
+
+func TestCombinatorsLockstep(t *testing.T) {
+	// Even when one of them has an error, the rest are still asked,
+	// so that they all stay in lockstep
+	under := NewLimit([]time.Duration{time.Second, time.Minute}, false)
+	bo := Max(StopOnAny, Ceiling(NewZero(), 0), under)
+
+	_, err := bo.Next(false)
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+
+	dur, err := under.Next(false)
+	if dur != time.Minute {
+		t.Errorf("expected %s: %s", time.Minute, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
+
+func TestSumSaturates(t *testing.T) {
+	huge := time.Duration(math.MaxInt64 - 10)
+	bo := Sum(StopOnAny, NewConstant(huge), NewConstant(time.Second))
+
+	dur, err := bo.Next(false)
+	if dur != time.Duration(math.MaxInt64) {
+		t.Errorf("expected %d: %d", int64(math.MaxInt64), dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
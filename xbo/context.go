@@ -0,0 +1,84 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"time"
+)
+
+// ContextBackOff is optionally implemented by a BackOff that makes use of
+// request-scoped data (e.g. a tenant, a priority, or hints from a server)
+// to decide. The Waiter prefers NextContext over Next when it is available.
+//
+// The decorators in this package only call Next on what they decorate, so
+// a ContextBackOff should be the outermost BackOff, to see the Context.
+type ContextBackOff interface {
+	BackOff
+	// NextContext is like Next, but for the request the Context belongs to
+	NextContext(ctx context.Context, reset bool) (time.Duration, error)
+}
+
+// The ContextBackOffFunc type is an adapter to allow the use of ordinary
+// functions as a ContextBackOff.
+type ContextBackOffFunc func(context.Context, bool) (time.Duration, error)
+
+// Next calls f(context.Background(), reset)
+func (f ContextBackOffFunc) Next(reset bool) (time.Duration, error) {
+	return f(context.Background(), reset)
+}
+
+// NextContext calls f(ctx, reset)
+func (f ContextBackOffFunc) NextContext(ctx context.Context, reset bool) (time.Duration, error) {
+	return f(ctx, reset)
+}
+
+type overrideKey struct{}
+
+// WithPolicyOverride gives a Context that carries a BackOff to be used in
+// place of the one configured (e.g. on a Waiter), for the request the
+// Context belongs to. A nil BackOff clears any override.
+func WithPolicyOverride(ctx context.Context, bo BackOff) context.Context {
+	return context.WithValue(ctx, overrideKey{}, bo)
+}
+
+// PolicyOverride reports the BackOff carried by the Context, if any.
+func PolicyOverride(ctx context.Context) (BackOff, bool) {
+	bo, ok := ctx.Value(overrideKey{}).(BackOff)
+	return bo, ok && bo != nil
+}
+
+// NextContext asks the BackOff what to do next, on behalf of the request
+// the Context belongs to. Any BackOff carried by the Context (see
+// WithPolicyOverride) takes precedence over the one given, and NextContext
+// is preferred over Next for any ContextBackOff.
+func NextContext(ctx context.Context, bo BackOff, reset bool) (time.Duration, error) {
+	if override, ok := PolicyOverride(ctx); ok {
+		// Only the first lookup sees the override, so that a ContextBackOff
+		// can itself consult what it decorates with NextContext
+		bo = override
+		ctx = WithPolicyOverride(ctx, nil)
+	}
+	if cbo, ok := bo.(ContextBackOff); ok {
+		return cbo.NextContext(ctx, reset)
+	}
+	return bo.Next(reset)
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func tenantBackOff() ContextBackOff {
+	return ContextBackOffFunc(func(ctx context.Context, reset bool) (time.Duration, error) {
+		if reset {
+			return ZeroDuration, nil
+		}
+		if ctx.Value(tenantKey{}) == "premium" {
+			return time.Millisecond, nil
+		}
+		return time.Second, nil
+	})
+}
+
+func TestNextContext(t *testing.T) {
+	bo := tenantBackOff()
+	premium := context.WithValue(context.Background(), tenantKey{}, "premium")
+
+	testCases := []struct {
+		ctx      context.Context
+		bo       BackOff
+		expected time.Duration
+	}{
+		// Plain BackOffs are asked as usual
+		{premium, NewConstant(time.Minute), time.Minute},
+		// ContextBackOffs get to see the Context
+		{context.Background(), bo, time.Second},
+		{premium, bo, time.Millisecond},
+		// An override takes precedence
+		{WithPolicyOverride(premium, NewConstant(time.Hour)), bo, time.Hour},
+		{WithPolicyOverride(premium, bo), NewConstant(time.Hour), time.Millisecond},
+		// Unless it's been cleared
+		{WithPolicyOverride(WithPolicyOverride(premium, NewConstant(time.Hour)), nil), bo, time.Millisecond},
+	}
+	for ix, tc := range testCases {
+		dur, err := NextContext(tc.ctx, tc.bo, false)
+		if dur != tc.expected {
+			t.Errorf("%d: expected %v: %v", ix, tc.expected, dur)
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected: %v", ix, err)
+		}
+	}
+
+	// Plain Next uses a background Context
+	dur, err := bo.Next(false)
+	if dur != time.Second {
+		t.Errorf("expected %v: %v", time.Second, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
+
+func TestPolicyOverride(t *testing.T) {
+	_, ok := PolicyOverride(context.Background())
+	if ok {
+		t.Errorf("unexpected override")
+	}
+
+	ctx := WithPolicyOverride(context.Background(), NewZero())
+	bo, ok := PolicyOverride(ctx)
+	if !ok || bo == nil {
+		t.Errorf("expected override")
+	}
+
+	_, ok = PolicyOverride(WithPolicyOverride(ctx, nil))
+	if ok {
+		t.Errorf("unexpected override")
+	}
+}
+
+func TestWaiterContext(t *testing.T) {
+	w, err := NewWaiter(NewConstant(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	ctx, cxl := context.WithTimeout(context.Background(), time.Second)
+	defer cxl()
+
+	// The per-request override keeps us from waiting an hour
+	start := time.Now()
+	err = w.Wait(WithPolicyOverride(ctx, NewConstant(time.Millisecond)), false)
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("expected less than %v: %v", time.Millisecond*500, elapsed)
+	}
+
+	// As does a ContextBackOff
+	w, err = NewWaiter(tenantBackOff())
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	start = time.Now()
+	err = w.Wait(context.WithValue(ctx, tenantKey{}, "premium"), false)
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("expected less than %v: %v", time.Millisecond*500, elapsed)
+	}
+}
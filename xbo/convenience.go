@@ -28,7 +28,7 @@ import "time"
 //
 // This is useful for testing.
 func NewConstant(d time.Duration) BackOff {
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	return stateless(func(reset bool) (time.Duration, error) {
 		if reset {
 			return ZeroDuration, nil
 		}
@@ -48,10 +48,10 @@ func NewZero() BackOff {
 //
 // This is useful for testing.
 func NewStop() BackOff {
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	return stateless(func(reset bool) (time.Duration, error) {
 		if reset {
 			return ZeroDuration, nil
 		}
-		return ZeroDuration, ErrStop
+		return ZeroDuration, &StopError{Reason: ReasonStopped, Source: "NewStop"}
 	})
 }
@@ -21,6 +21,7 @@
 package xbo
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -74,7 +75,7 @@ func TestConvenienceBackOffs(t *testing.T) {
 				if dur != tc.dur {
 					t.Errorf("expected %s: %s", tc.dur, dur)
 				}
-				if err != tc.err {
+				if !errors.Is(err, tc.err) {
 					t.Errorf("expected %v: %v", tc.err, err)
 				}
 			}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+// Decay decides how far back a reset takes a BackOff that counts its
+// attempts (NewExponential, NewLoop, NewLimit, NewEcho), given how many
+// attempts it had counted. The usual reset takes it all the way back to
+// zero, so that a single success has a flapping dependency hammered again
+// straight away; a Decay lets it ease off more gradually.
+//
+// A Decay can only take the count back, never forward, and a nil Decay is
+// the usual reset.
+type Decay func(attempts uint32) uint32
+
+// DecayStep creates a Decay that takes the count back by n attempts on each
+// reset (but no further back than zero).
+func DecayStep(n uint32) Decay {
+	return Decay(func(attempts uint32) uint32 {
+		if attempts < n {
+			return 0
+		}
+		return attempts - n
+	})
+}
+
+// DecayHalve creates a Decay that halves the count on each reset.
+func DecayHalve() Decay {
+	return Decay(func(attempts uint32) uint32 {
+		return attempts / 2
+	})
+}
+
+// apply works out the count after a reset, keeping custom Decays honest
+func (d Decay) apply(attempts uint32) uint32 {
+	if d == nil {
+		return 0
+	}
+	result := d(attempts)
+	if result > attempts {
+		return attempts
+	}
+	return result
+}
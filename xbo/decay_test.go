@@ -0,0 +1,129 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecays(t *testing.T) {
+	testCases := []struct {
+		decay    Decay
+		attempts uint32
+		expected uint32
+	}{
+		{nil, 7, 0},
+		{DecayStep(2), 7, 5},
+		{DecayStep(2), 1, 0},
+		{DecayStep(0), 7, 7},
+		{DecayHalve(), 7, 3},
+		{DecayHalve(), 1, 0},
+		// Never forwards
+		{func(attempts uint32) uint32 { return attempts + 1 }, 7, 7},
+	}
+	for ix, tc := range testCases {
+		actual := tc.decay.apply(tc.attempts)
+		if actual != tc.expected {
+			t.Errorf("%d: expected %d: %d", ix, tc.expected, actual)
+		}
+	}
+}
+
+func checkNext(t *testing.T, bo BackOff, expected ...time.Duration) {
+	t.Helper()
+	for _, exp := range expected {
+		dur, err := bo.Next(false)
+		if dur != exp {
+			t.Errorf("expected %v: %v", exp, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
+
+func TestExponentialDecay(t *testing.T) {
+	s := time.Second
+	for _, safe := range []bool{false, true} {
+		bo, err := NewExponential(s, 1.0, ExponentialDecay(DecayHalve()), ExponentialSafe(safe))
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+
+		checkNext(t, bo, s, s*2, s*4, s*8)
+
+		// Four attempts, halved to two, so we pick up with the third
+		bo.Next(true)
+		checkNext(t, bo, s*4)
+
+		// Three attempts, halved to one
+		bo.Next(true)
+		checkNext(t, bo, s*2)
+
+		// Eventually we get all the way back
+		bo.Next(true)
+		bo.Next(true)
+		checkNext(t, bo, s)
+	}
+}
+
+func TestSequenceDecay(t *testing.T) {
+	s := time.Second
+	durs := []time.Duration{s, s * 2, s * 3}
+	for _, safe := range []bool{false, true} {
+		// Running past the end of the list still only counts as the end
+		bo := NewLimit(durs, safe, SequenceDecay(DecayStep(1)))
+		checkNext(t, bo, durs...)
+		bo.Next(false)
+		bo.Next(false)
+		bo.Next(true)
+		checkNext(t, bo, s*3)
+
+		bo = NewEcho(durs, safe, SequenceDecay(DecayStep(2)))
+		checkNext(t, bo, s, s*2, s*3, s*3)
+		bo.Next(true)
+		checkNext(t, bo, s*2)
+
+		// Loops decay from where they are in the current loop
+		bo = NewLoop(durs, safe, SequenceDecay(DecayStep(1)))
+		checkNext(t, bo, s, s*2, s*3, s, s*2)
+		bo.Next(true)
+		checkNext(t, bo, s*2, s*3)
+
+		// A nil Decay is a hard reset
+		bo = NewLimit(durs, safe, SequenceDecay(nil))
+		checkNext(t, bo, s, s*2)
+		bo.Next(true)
+		checkNext(t, bo, s)
+	}
+}
+
+func TestDecaySafe(t *testing.T) {
+	bo, err := NewExponential(time.Millisecond, 1.0, ExponentialDecay(DecayHalve()), ExponentialSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	_, stops := hammer(t, Ceiling(bo, time.Hour), 8, 500, 7)
+	if stops != 0 {
+		t.Errorf("unexpected stops: %d", stops)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Decider is an alternative to the BackOff interface, which keeps resets
+// apart from asking what to do next, and has more to say about what to do.
+// Use ToDecider and FromDecider to go back and forth between the two, so
+// that everything in this package works in either style.
+type Decider interface {
+	// Next decides what to do ahead of the next attempt. If an error
+	// matching ErrStop is returned, no further attempts should be made.
+	Next() (Decision, error)
+	// Reset starts the sequence of decisions over from the beginning.
+	Reset()
+}
+
+// Decision is what a Decider has to say about the next attempt.
+type Decision struct {
+	// Delay is how long to wait before the next attempt
+	Delay time.Duration
+	// Attempt counts the calls to Next since the last Reset, including
+	// this one. Where the source keeps its own count (NewExponential,
+	// NewLoop, NewLimit, NewEcho, MaxAttempts, Elapsed), this is that
+	// count, which carries on from wherever a Decay left it.
+	Attempt uint64
+	// Reason says why no further attempts should be made, when Next
+	// returns an error matching ErrStop (and is otherwise empty)
+	Reason Reason
+}
+
+// ToDecider adapts a BackOff to the Decider interface. Calls to the Decider
+// are made one at a time, so it is safe for concurrent use (as long as the
+// BackOff is not also being used directly elsewhere).
+//
+// The built-ins that keep their own count of attempts (see Decision) give
+// that count in each Decision; otherwise the Decider keeps count itself.
+// If the BackOff returns an error on being reset, that is returned by the
+// following call to Next. The Decider has no way to hand out a delay the
+// BackOff suggests on being reset (e.g. Spread), so use the BackOff directly
+// for those.
+func ToDecider(bo BackOff) Decider {
+	if d, ok := bo.(*fromDecider); ok {
+		return d.d
+	}
+	return &toDecider{bo: bo}
+}
+
+// decider is implemented by the built-ins that keep their own count of
+// attempts. The decide method is the same as calling Next(false), but
+// with a Decision to show for it.
+type decider interface {
+	decide() (Decision, error)
+}
+
+// counting pairs one of our BackOffFunc-based BackOffs with its decide
+type counting struct {
+	analyzable
+	decideFunc func() (Decision, error)
+}
+
+func (c counting) decide() (Decision, error) {
+	return c.decideFunc()
+}
+
+type toDecider struct {
+	bo      BackOff
+	mu      sync.Mutex
+	attempt uint64
+	pending error
+}
+
+// Next conforms to the Decider interface
+func (t *toDecider) Next() (Decision, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempt++
+	result := Decision{Attempt: t.attempt}
+	err := t.pending
+	t.pending = nil
+
+	if err == nil {
+		if d, ok := t.bo.(decider); ok {
+			result, err = d.decide()
+		} else {
+			result.Delay, err = t.bo.Next(false)
+		}
+	}
+
+	var stop *StopError
+	switch {
+	case errors.As(err, &stop):
+		result.Reason = stop.Reason
+	case errors.Is(err, ErrStop):
+		result.Reason = ReasonStopped
+	}
+	return result, err
+}
+
+// Reset conforms to the Decider interface
+func (t *toDecider) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := t.bo.Next(true)
+	t.attempt = 0
+	t.pending = err
+}
+
+// FromDecider adapts a Decider to the BackOff interface.
+func FromDecider(d Decider) BackOff {
+	if t, ok := d.(*toDecider); ok {
+		return t.bo
+	}
+	return &fromDecider{d: d}
+}
+
+type fromDecider struct {
+	d Decider
+}
+
+// Next conforms to the BackOff interface
+func (f *fromDecider) Next(reset bool) (time.Duration, error) {
+	if reset {
+		f.d.Reset()
+		return ZeroDuration, nil
+	}
+	decision, err := f.d.Next()
+	if err != nil {
+		return ZeroDuration, err
+	}
+	return decision.Delay, nil
+}
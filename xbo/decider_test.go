@@ -0,0 +1,196 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestToDecider(t *testing.T) {
+	s := time.Second
+	d := ToDecider(MaxAttempts(NewLimit([]time.Duration{s, s * 2, s * 3}, false), 2, false))
+
+	for cycle := 0; cycle < 2; cycle++ {
+		expected := []Decision{
+			{Delay: s, Attempt: 1},
+			{Delay: s * 2, Attempt: 2},
+			{Attempt: 3, Reason: ReasonAttempts},
+		}
+		for ix, exp := range expected {
+			decision, err := d.Next()
+			if decision != exp {
+				t.Errorf("%d: expected %+v: %+v", ix, exp, decision)
+			}
+			if exp.Reason == "" && err != nil {
+				t.Errorf("%d: unexpected: %v", ix, err)
+			}
+			if exp.Reason != "" && !errors.Is(err, ErrStop) {
+				t.Errorf("%d: expected %v: %v", ix, ErrStop, err)
+			}
+		}
+		d.Reset()
+	}
+}
+
+func TestToDeciderOwnCount(t *testing.T) {
+	s := time.Second
+	exp, err := NewExponential(s, 1.0, ExponentialDecay(DecayHalve()))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	d := ToDecider(exp)
+	for ix := 0; ix < 4; ix++ {
+		d.Next()
+	}
+
+	// Four attempts, halved to two, so we pick up with the third
+	d.Reset()
+	decision, err := d.Next()
+	expected := Decision{Delay: s * 4, Attempt: 3}
+	if decision != expected {
+		t.Errorf("expected %+v: %+v", expected, decision)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+
+	// Elapsed counts too
+	d = ToDecider(Elapsed(NewLimit([]time.Duration{s, s * 2}, false), time.Hour))
+	for ix, exp := range []Decision{{Delay: s, Attempt: 1}, {Delay: s * 2, Attempt: 2}} {
+		decision, err := d.Next()
+		if decision != exp {
+			t.Errorf("%d: expected %+v: %+v", ix, exp, decision)
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected: %v", ix, err)
+		}
+	}
+}
+
+func TestToDeciderConcurrent(t *testing.T) {
+	exp, err := NewExponential(time.Nanosecond, 1.0, ExponentialSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	// Whoever asks, the attempt and the delay go together
+	for _, bo := range []BackOff{exp, Ceiling(exp, time.Hour)} {
+		bo.Next(true)
+		d := ToDecider(bo)
+		var wg sync.WaitGroup
+		for ix := 0; ix < 8; ix++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for jx := 0; jx < 4; jx++ {
+					decision, err := d.Next()
+					if err != nil {
+						t.Errorf("unexpected: %v", err)
+					}
+					expected := time.Duration(1) << (decision.Attempt - 1)
+					if decision.Delay != expected {
+						t.Errorf("expected %v: %+v", expected, decision)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestToDeciderErrors(t *testing.T) {
+	// A plain ErrStop still has a reason
+	d := ToDecider(BackOffFunc(func(reset bool) (time.Duration, error) {
+		return ZeroDuration, ErrStop
+	}))
+	decision, err := d.Next()
+	if decision.Reason != ReasonStopped {
+		t.Errorf("expected %q: %q", ReasonStopped, decision.Reason)
+	}
+	if err != ErrStop {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	// Errors on reset come out of the next decision
+	d = ToDecider(Ceiling(NewZero(), 0))
+	d.Reset()
+	_, err = d.Next()
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+}
+
+type countdown struct {
+	left uint64
+	from uint64
+}
+
+func (c *countdown) Next() (Decision, error) {
+	if c.left == 0 {
+		return Decision{Attempt: c.from + 1, Reason: ReasonExhausted},
+			&StopError{Reason: ReasonExhausted, Source: "countdown"}
+	}
+	c.left--
+	return Decision{Delay: time.Duration(c.left), Attempt: c.from - c.left}, nil
+}
+
+func (c *countdown) Reset() {
+	c.left = c.from
+}
+
+func TestFromDecider(t *testing.T) {
+	bo := FromDecider(&countdown{left: 3, from: 3})
+
+	// It can be decorated like any other BackOff
+	bo = Floor(bo, 1)
+	checkNext(t, bo, 2, 1, 1)
+	_, err := bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	dur, err := bo.Next(true)
+	if dur != ZeroDuration {
+		t.Errorf("expected %v: %v", ZeroDuration, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+	checkNext(t, bo, 2)
+}
+
+func TestDeciderRoundTrip(t *testing.T) {
+	bo, err := NewExponential(time.Second, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if FromDecider(ToDecider(bo)) != bo {
+		t.Errorf("expected the original BackOff")
+	}
+
+	d := Decider(&countdown{})
+	if ToDecider(FromDecider(d)) != d {
+		t.Errorf("expected the original Decider")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Distribution decides where, within the range allowed by the jitter
+// options, each result lands. It returns a position between 0.0 (the bottom
+// of the range) and 1.0 (the top of the range), drawing on the JitterRand
+// for its randomness. Positions outside of that are clipped.
+//
+// Without a Distribution, jitter is uniformly distributed.
+type Distribution func(r JitterRand) float64
+
+// clipPosition keeps the position returned by a Distribution in range
+func clipPosition(position float64) float64 {
+	switch {
+	case math.IsNaN(position) || position < 0:
+		return 0
+	case position > 1:
+		return 1
+	}
+	return position
+}
+
+// unitFloat draws a uniformly distributed number in [0.0, 1.0)
+func unitFloat(r JitterRand) float64 {
+	return float64(r.Int63n(1<<53)) / (1 << 53)
+}
+
+// JitterDistribution option allows the consumer to define the shape of the
+// randomness applied.
+func JitterDistribution(d Distribution) JitterOption {
+	return JitterOption(func(j *jitter) error {
+		if d == nil {
+			return fmt.Errorf("nil distribution")
+		}
+		j.dist = d
+		return nil
+	})
+}
+
+// JitterGaussian option makes the jitter normally distributed around the
+// middle of the range, with the standard deviation given as a fraction of
+// the width of the range. Anything that lands outside the range is clipped
+// to the nearest end of it.
+func JitterGaussian(stddev float64) JitterOption {
+	if math.IsNaN(stddev) || math.IsInf(stddev, 0) || stddev <= 0 {
+		return invalidDistribution("stddev", stddev)
+	}
+	return JitterDistribution(func(r JitterRand) float64 {
+		// Box-Muller transform; (1 - u) keeps us away from log(0)
+		u1, u2 := unitFloat(r), unitFloat(r)
+		z := math.Sqrt(-2*math.Log(1-u1)) * math.Cos(2*math.Pi*u2)
+		return 0.5 + stddev*z
+	})
+}
+
+// JitterExponential option makes the jitter exponentially distributed up
+// from the bottom of the range, with the mean given as a fraction of the
+// width of the range, much like the gaps between arrivals in a Poisson
+// process. Anything that lands beyond the top of the range is clipped to it.
+func JitterExponential(mean float64) JitterOption {
+	if math.IsNaN(mean) || math.IsInf(mean, 0) || mean <= 0 {
+		return invalidDistribution("mean", mean)
+	}
+	return JitterDistribution(func(r JitterRand) float64 {
+		return -mean * math.Log(1-unitFloat(r))
+	})
+}
+
+// JitterTriangular option makes the jitter follow a triangular distribution
+// across the range, peaking at the mode, which is given as a fraction of the
+// width of the range (so 0.5 peaks in the middle).
+func JitterTriangular(mode float64) JitterOption {
+	if math.IsNaN(mode) || mode < 0 || mode > 1 {
+		return invalidDistribution("mode", mode)
+	}
+	return JitterDistribution(func(r JitterRand) float64 {
+		// Inverse of the cumulative distribution function
+		u := unitFloat(r)
+		if u < mode {
+			return math.Sqrt(u * mode)
+		}
+		return 1 - math.Sqrt((1-u)*(1-mode))
+	})
+}
+
+// JitterSeededBy option makes the jitter land in the same spot within the
+// range every time, based on a hash of the identity given (e.g. a hostname
+// or agent ID). Across a fleet, each member gets its own stable spot, so
+// they stay spread out, even after they all restart at once.
+func JitterSeededBy(id string) JitterOption {
+	position := identityFraction(id)
+	return JitterDistribution(func(JitterRand) float64 {
+		return position
+	})
+}
+
+// identityFraction deterministically turns the identity into a number
+// in [0.0, 1.0)
+func identityFraction(id string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return float64(mix64(h.Sum64())>>11) / (1 << 53)
+}
+
+// mix64 is the murmur3 (fmix64) finalizer. FNV barely moves the high bits
+// for identities that only differ in their last few characters (e.g.
+// agent-1, agent-2), so they need mixing before we take those bits.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func invalidDistribution(name string, value float64) JitterOption {
+	return JitterOption(func(j *jitter) error {
+		return fmt.Errorf("invalid distribution %s: %f", name, value)
+	})
+}
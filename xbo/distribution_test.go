@@ -0,0 +1,230 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDistributionErrors(t *testing.T) {
+	opts := []JitterOption{
+		JitterDistribution(nil),
+		JitterGaussian(0),
+		JitterGaussian(math.NaN()),
+		JitterGaussian(math.Inf(1)),
+		JitterExponential(-1),
+		JitterExponential(math.NaN()),
+		JitterTriangular(-0.1),
+		JitterTriangular(1.1),
+		JitterTriangular(math.NaN()),
+	}
+
+	for _, opt := range opts {
+		bo, err := NewJitter(NewZero(), JitterOver(10), opt)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if bo != nil {
+			t.Errorf("unexpected: %v", bo)
+		}
+	}
+}
+
+// sample draws many results from a jitter BackOff spanning [0s, 1000s], and
+// gives them back as positions within that range
+func sample(t *testing.T, opt JitterOption) []float64 {
+	base := time.Second * 500
+	bo, err := NewJitter(NewConstant(base),
+		JitterUnder(100),
+		JitterOver(100),
+		JitterRandomizer(rand.New(rand.NewSource(42))),
+		opt,
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	var result []float64
+	for ix := 0; ix < 20000; ix++ {
+		dur, err := bo.Next(false)
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		if dur < 0 || dur > base*2 {
+			t.Fatalf("out of range: %v", dur)
+		}
+		result = append(result, float64(dur)/float64(base*2))
+	}
+	return result
+}
+
+func moments(xs []float64) (float64, float64) {
+	var sum, sq float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(xs)))
+}
+
+func within(t *testing.T, name string, actual float64, expected float64) {
+	if math.Abs(actual-expected) > 0.01 {
+		t.Errorf("%s expected %f: %f", name, expected, actual)
+	}
+}
+
+func TestDistributionUniform(t *testing.T) {
+	xs := sample(t, JitterDistribution(func(r JitterRand) float64 {
+		return unitFloat(r)
+	}))
+	mean, stddev := moments(xs)
+	within(t, "mean", mean, 0.5)
+	within(t, "stddev", stddev, 1/math.Sqrt(12))
+}
+
+func TestDistributionGaussian(t *testing.T) {
+	xs := sample(t, JitterGaussian(0.1))
+	mean, stddev := moments(xs)
+	within(t, "mean", mean, 0.5)
+	within(t, "stddev", stddev, 0.1)
+
+	// About 68% should be within one standard deviation
+	inside := 0
+	for _, x := range xs {
+		if x >= 0.4 && x <= 0.6 {
+			inside++
+		}
+	}
+	within(t, "one sigma", float64(inside)/float64(len(xs)), 0.6827)
+
+	// A wide one gets clipped to the range, piling up at the ends
+	xs = sample(t, JitterGaussian(1.0))
+	ends := 0
+	for _, x := range xs {
+		if x == 0 || x == 1 {
+			ends++
+		}
+	}
+	if ends == 0 {
+		t.Errorf("expected clipping at the ends")
+	}
+}
+
+func TestDistributionExponential(t *testing.T) {
+	xs := sample(t, JitterExponential(0.1))
+	mean, stddev := moments(xs)
+	within(t, "mean", mean, 0.1)
+	within(t, "stddev", stddev, 0.1)
+
+	// Memorylessness: about e^-1 should be beyond the mean
+	beyond := 0
+	for _, x := range xs {
+		if x > 0.1 {
+			beyond++
+		}
+	}
+	within(t, "beyond", float64(beyond)/float64(len(xs)), math.Exp(-1))
+}
+
+func TestDistributionTriangular(t *testing.T) {
+	modes := []float64{0, 0.25, 0.5, 1}
+	for _, mode := range modes {
+		xs := sample(t, JitterTriangular(mode))
+		mean, stddev := moments(xs)
+		within(t, "mean", mean, (1+mode)/3)
+		variance := (1 + mode*mode - mode) / 18
+		within(t, "stddev", stddev, math.Sqrt(variance))
+	}
+}
+
+func TestDistributionClipped(t *testing.T) {
+	positions := []struct {
+		position float64
+		dur      time.Duration
+	}{
+		{-1, time.Second * 5},
+		{math.NaN(), time.Second * 5},
+		{0.5, time.Second * 10},
+		{2, time.Second * 15},
+	}
+	for _, p := range positions {
+		position := p.position
+		bo, err := NewJitter(NewConstant(time.Second*10),
+			JitterUnder(50),
+			JitterOver(50),
+			JitterDistribution(func(JitterRand) float64 { return position }),
+		)
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		dur, err := bo.Next(false)
+		if dur != p.dur {
+			t.Errorf("expected %v: %v", p.dur, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
+
+func TestJitterSeededBy(t *testing.T) {
+	base := time.Second * 10
+	build := func(id string) BackOff {
+		bo, err := NewJitter(NewConstant(base),
+			JitterUnder(50),
+			JitterOver(50),
+			JitterSeededBy(id),
+		)
+		if err != nil {
+			t.Fatalf("unexpected: %v", err)
+		}
+		return bo
+	}
+
+	// Restarting (i.e. building it again) lands us in the same spot
+	first, again, other := build("agent-1"), build("agent-1"), build("agent-2")
+	expected, _ := first.Next(false)
+	for ix := 0; ix < 5; ix++ {
+		for _, bo := range []BackOff{first, again} {
+			dur, err := bo.Next(false)
+			if dur != expected {
+				t.Errorf("expected %v: %v", expected, dur)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+		}
+	}
+
+	dur, _ := other.Next(false)
+	if dur == expected {
+		t.Errorf("expected different identities to differ: %v", dur)
+	}
+	if dur < base/2 || dur > base*3/2 {
+		t.Errorf("out of range: %v", dur)
+	}
+}
@@ -25,4 +25,18 @@
 // https://github.com/jpillora/backoff),
 // but with an increased focus on being composable and extensible, and to
 // provide concurrency-safe options.
+//
+// Every BackOff that keeps state between calls can be made safe for
+// concurrent use, either with a safe parameter (e.g. NewLimit, MaxAttempts,
+// Concat) or a functional option (e.g. ExponentialSafe, JitterSafe). Those
+// that keep no state of their own (e.g. Ceiling, Max, Scale) are exactly as
+// safe as the BackOffs they decorate. When not asked to be safe, a BackOff
+// must only be used by one goroutine at a time.
+//
+// Everything is built around the BackOff interface, where a reset is a call
+// to Next(true). The Decider interface is an alternative which keeps resets
+// apart, and says more about each decision; ToDecider and FromDecider adapt
+// between the two. The built-ins that count attempts (e.g. NewExponential,
+// MaxAttempts) share their own count with the Decider, but any delay
+// suggested on a reset (e.g. by Spread) is only available through BackOff.
 package xbo
@@ -32,6 +32,7 @@ type exponential struct {
 	seed   float64
 	factor float64
 	safe   bool
+	decay  Decay
 }
 
 // NewExponential creates a BackOff that will increase the suggested
@@ -70,21 +71,41 @@ func (x *exponential) Next(reset bool) (time.Duration, error) {
 		return ZeroDuration, nil
 	}
 
+	d, err := x.decide()
+	return d.Delay, err
+}
+
+func (x *exponential) decide() (Decision, error) {
 	// seed * (factor**exponent)
-	// TODO: Check for, and error, if we are overflowing int64
+	// (Rather than overflowing int64, this tops out at the largest
+	// duration we can represent.)
 	exponent := x.incr()
 	multiplier := math.Pow(x.factor, float64(exponent))
-	result := time.Duration(x.seed * multiplier)
+	result := scaleDuration(time.Duration(x.seed), multiplier)
 
-	return result, nil
+	return Decision{Delay: result, Attempt: uint64(exponent) + 1}, nil
+}
+
+func (x *exponential) shadow(estimate, sinceFunc) (BackOff, error) {
+	return &exponential{count: -1, seed: x.seed, factor: x.factor, decay: x.decay}, nil
 }
 
 func (x *exponential) zero() {
 	if x.safe {
-		atomic.StoreInt32(&x.count, -1)
-		return
+		for {
+			count := atomic.LoadInt32(&x.count)
+			if atomic.CompareAndSwapInt32(&x.count, count, x.rewind(count)) {
+				return
+			}
+		}
 	}
-	x.count = -1
+	x.count = x.rewind(x.count)
+}
+
+// rewind works out where the count goes back to on a reset (remembering
+// that the count is the exponent last used, so starts out at -1)
+func (x *exponential) rewind(count int32) int32 {
+	return int32(x.decay.apply(uint32(count+1))) - 1
 }
 
 func (x *exponential) incr() int32 {
@@ -107,3 +128,12 @@ func ExponentialSafe(safe bool) ExponentialOption {
 		return nil
 	})
 }
+
+// ExponentialDecay makes resets take the BackOff back gradually, according
+// to the Decay, rather than all the way to the initial duration.
+func ExponentialDecay(decay Decay) ExponentialOption {
+	return ExponentialOption(func(x *exponential) error {
+		x.decay = decay
+		return nil
+	})
+}
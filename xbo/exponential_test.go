@@ -79,3 +79,28 @@ func TestExponentialCheckInputs(t *testing.T) {
 		}
 	}
 }
+
+func TestExponentialSafe(t *testing.T) {
+	x, err := NewExponential(time.Nanosecond, 1.0, ExponentialSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	// Each step in the sequence should be handed out exactly once
+	got, _ := hammer(t, x, 10, 5, 0)
+	seen := map[time.Duration]bool{}
+	for _, dur := range got {
+		if seen[dur] {
+			t.Errorf("duplicate: %v", dur)
+		}
+		seen[dur] = true
+	}
+	for ix := uint(0); ix < 50; ix++ {
+		if !seen[time.Duration(1)<<ix] {
+			t.Errorf("missing: %v", time.Duration(1)<<ix)
+		}
+	}
+
+	// And resets in the mix don't cause any trouble
+	hammer(t, x, 200, 50, 3)
+}
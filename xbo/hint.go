@@ -0,0 +1,94 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"sync"
+	"time"
+)
+
+// HintMode declares how a HintedBackOff makes use of a hint.
+type HintMode int
+
+const (
+	// HintAtLeast waits for the longer of the hint, and what the
+	// underlying BackOff says
+	HintAtLeast HintMode = iota
+	// HintExact waits for exactly as long as the hint says
+	HintExact
+)
+
+// HintedBackOff is a BackOff decorator that can be told how long to wait
+// next by a source outside of the BackOff, e.g. an HTTP Retry-After header,
+// or gRPC pushback. It is safe for concurrent use, as long as the
+// underlying BackOff is.
+type HintedBackOff struct {
+	bo   BackOff
+	mode HintMode
+	mu   sync.Mutex
+	hint time.Duration
+	set  bool
+}
+
+// Hinted creates a HintedBackOff around the underlying BackOff.
+func Hinted(bo BackOff, mode HintMode) *HintedBackOff {
+	return &HintedBackOff{bo: bo, mode: mode}
+}
+
+// Hint applies to the next call to Next (replacing any hint not yet used),
+// unless there is a reset first. Negative hints are ignored.
+func (h *HintedBackOff) Hint(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hint = d
+	h.set = true
+}
+
+// Next conforms to the BackOff interface. The underlying BackOff is always
+// consulted, so that it carries on with its own schedule once the hint has
+// been used, and so that it can still say to stop.
+func (h *HintedBackOff) Next(reset bool) (time.Duration, error) {
+	dur, err := h.bo.Next(reset)
+
+	h.mu.Lock()
+	hint, set := h.hint, h.set
+	h.hint, h.set = ZeroDuration, false
+	h.mu.Unlock()
+
+	if reset || err != nil || !set {
+		return dur, err
+	}
+	if h.mode == HintExact || hint > dur {
+		return hint, nil
+	}
+	return dur, nil
+}
+
+func (h *HintedBackOff) shadow(e estimate, now sinceFunc) (BackOff, error) {
+	inner, err := shadowOf(h.bo, e, now)
+	if err != nil {
+		return nil, err
+	}
+	return Hinted(inner, h.mode), nil
+}
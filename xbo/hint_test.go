@@ -0,0 +1,89 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHintedAtLeast(t *testing.T) {
+	s := time.Second
+	exp, err := NewExponential(s, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo := Hinted(exp, HintAtLeast)
+
+	checkNext(t, bo, s)
+
+	// A longer hint wins, but only the once
+	bo.Hint(s * 10)
+	checkNext(t, bo, s*10, s*4)
+
+	// A shorter hint doesn't get to cut the wait
+	bo.Hint(s)
+	checkNext(t, bo, s*8)
+
+	// Negative hints are ignored
+	bo.Hint(-s)
+	checkNext(t, bo, s*16)
+
+	// Hints are dropped by resets
+	bo.Hint(time.Hour)
+	bo.Next(true)
+	checkNext(t, bo, s)
+}
+
+func TestHintedExact(t *testing.T) {
+	s := time.Second
+	bo := Hinted(NewLimit([]time.Duration{s, s * 2, s * 3}, false), HintExact)
+
+	bo.Hint(ZeroDuration)
+	checkNext(t, bo, ZeroDuration, s*2)
+
+	bo.Hint(s * 10)
+	checkNext(t, bo, s*10)
+
+	// Hints don't keep the BackOff from stopping
+	bo.Hint(s)
+	_, err := bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+}
+
+func TestHintedSafe(t *testing.T) {
+	bo := Hinted(NewLoop([]time.Duration{1, 2, 3, 4, 5}, true), HintAtLeast)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ix := 0; ix < 1000; ix++ {
+			bo.Hint(time.Duration(ix))
+		}
+	}()
+	_, stops := hammer(t, bo, 8, 500, 0)
+	<-done
+	if stops != 0 {
+		t.Errorf("unexpected stops: %d", stops)
+	}
+}
@@ -26,7 +26,9 @@ import (
 )
 
 // ErrStop is the sentinel value that is returned when the
-// calculations say no further attempts should be made
+// calculations say no further attempts should be made. The BackOffs in
+// this package return a StopError that explains why, so check for this
+// with errors.Is(err, ErrStop).
 var ErrStop = fmt.Errorf("stop any further attempts")
 
 // ZeroDuration is what is returned when requesting a reset
@@ -37,8 +39,9 @@ var ZeroDuration = time.Duration(0)
 type BackOff interface {
 	// Next returns the amount of time that should be
 	// waited until the next attempt.
-	// If an xbo.ErrStop is returned, that is the signal
-	// that no further attempts should be made
+	// If an xbo.ErrStop (or an error that matches it, like a
+	// StopError) is returned, that is the signal that no
+	// further attempts should be made.
 	// Sending a reset value of true means you want to
 	// start the sequence of values over again from the
 	// beginning. When being reset, it is customary for
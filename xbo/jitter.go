@@ -21,25 +21,20 @@
 package xbo
 
 import (
-	crand "crypto/rand"
-	"encoding/binary"
 	"fmt"
-	mrand "math/rand"
+	"math"
 	"time"
 )
 
-// JitterRand isolates the one function that we need from the
-// math/rand.Rand type. The user may choose to implement their own
-// randomization source, as long as it fulfills this interface.
-type JitterRand interface {
-	Int63n(n int64) int64
-}
-
 type jitter struct {
-	bo    BackOff
-	r     JitterRand
-	under uint8
-	over  uint8
+	bo       BackOff
+	r        JitterRand
+	under    float64
+	over     float64
+	underAbs time.Duration
+	overAbs  time.Duration
+	dist     Distribution
+	safe     bool
 }
 
 // NewJitter creates a decoration around an underlying BackOff, which adds
@@ -61,54 +56,85 @@ func NewJitter(bo BackOff, options ...JitterOption) (BackOff, error) {
 		}
 	}
 
-	if result.under == 0 && result.over == 0 {
+	if result.under == 0 && result.over == 0 &&
+		result.underAbs == 0 && result.overAbs == 0 {
 		return nil, fmt.Errorf("jitter over and under not defined")
 	}
 
-	// If no random source has been applied, create our own
+	// If no random source has been applied, use our own (which is
+	// already safe for concurrent use)
 	if result.r == nil {
-		r, err := randomlySeededRand()
-		if err != nil {
-			return nil, err
-		}
-		result.r = r
+		result.r = NewPooledRand()
+	} else if result.safe {
+		// The math/rand.Rand type is not safe for concurrent use, so if
+		// we have been asked to be safe, we have to guard it ourselves
+		result.r = NewLockedRand(result.r)
 	}
 
 	return result, nil
 }
 
-// I've seen other utilities just use time.Now().UnixNano() to seed
-// their random, but here we are using a randomly-generated seed,
-// because the whole point of adding jitter is to reduce likelihood of
-// accidental synchronization.
-func randomlySeededRand() (*mrand.Rand, error) {
-	b := make([]byte, 8)
-	_, err := crand.Reader.Read(b)
-	if err != nil {
-		return nil, err
-	}
-	seed := int64(binary.BigEndian.Uint64(b))
-	return mrand.New(mrand.NewSource(seed)), nil
-}
-
 func (j *jitter) Next(reset bool) (time.Duration, error) {
 	// We don't short-circuit, we always need to know the underlying results
 	dur, err := j.bo.Next(reset)
 
 	// But we only have work to do if it's not reset, not an error,
-	// and has a non-zero duration.
-	if reset || err != nil || dur <= 0 {
+	// and has a sensible duration.
+	if reset || err != nil || dur < 0 {
 		return dur, err
 	}
 
-	// Calculate the range of result
-	min := (dur.Nanoseconds() * int64(100-j.under)) / 100
-	max := (dur.Nanoseconds() * int64(100+j.over)) / 100
+	// Calculate the range of result, being careful not to overflow
+	// for very large durations
+	min := dur - addDurations(scaleDuration(dur, j.under), j.underAbs)
+	if min < 0 {
+		min = 0
+	}
+	max := addDurations(dur, addDurations(scaleDuration(dur, j.over), j.overAbs))
+
+	// When we've been given a particular shape, find where in the
+	// range we landed
+	if j.dist != nil {
+		position := clipPosition(j.dist(j.r))
+		offset := scaleDuration(max-min, position)
+		if offset > max-min {
+			offset = max - min
+		}
+		return min + offset, nil
+	}
 
 	// Add in a dash of randomness, et voila!
-	// (Add one, because result range does not include the max number.)
-	offset := j.r.Int63n(max - min + 1)
-	return time.Duration(min + offset), nil
+	// (Add one, because result range does not include the max number,
+	// unless that would overflow, in which case we'll never quite
+	// reach the max.)
+	span := int64(max - min)
+	if span < math.MaxInt64 {
+		span++
+	}
+	offset := j.r.Int63n(span)
+	return min + time.Duration(offset), nil
+}
+
+func (j *jitter) shadow(e estimate, now sinceFunc) (BackOff, error) {
+	inner, err := shadowOf(j.bo, e, now)
+	if err != nil {
+		return nil, err
+	}
+
+	shadow := *j
+	shadow.bo = inner
+	shadow.r = e
+	if j.dist != nil {
+		position := 0.0
+		switch e {
+		case estimateHigh:
+			position = 1.0
+		case estimateMean:
+			position = meanPosition(j.dist)
+		}
+		shadow.dist = func(JitterRand) float64 { return position }
+	}
+	return &shadow, nil
 }
 
 // JitterOption declares the functional options for changing behavior on
@@ -117,7 +143,8 @@ type JitterOption func(*jitter) error
 
 // JitterRandomizer gives the consumer the option of specifying the
 // source of randomness for calculations. The JitterRand interface
-// directly applies to the math/rand.Rand type.
+// directly applies to the math/rand.Rand type (which is not safe for
+// concurrent use; see JitterSafe and NewLockedRand).
 func JitterRandomizer(r JitterRand) JitterOption {
 	return JitterOption(func(j *jitter) error {
 		if r == nil {
@@ -135,7 +162,7 @@ func JitterUnder(percent uint8) JitterOption {
 		if percent > 100 {
 			return fmt.Errorf("cannot jitter under 100 percent")
 		}
-		j.under = percent
+		j.under = float64(percent) / 100
 		return nil
 	})
 }
@@ -147,7 +174,52 @@ func JitterOver(percent uint8) JitterOption {
 		if percent > 100 {
 			return fmt.Errorf("cannot jitter over 100 percent")
 		}
-		j.over = percent
+		j.over = float64(percent) / 100
+		return nil
+	})
+}
+
+// JitterFraction option allows the consumer to define the maximum reduction
+// and increase applied to the duration delivered by the underlying BackOff,
+// as fractions of that duration. For example, under and over of 0.025 means
+// plus or minus 2.5%, and an over of 3.0 means up to three times longer.
+// The under fraction cannot be more than 1.0.
+func JitterFraction(under float64, over float64) JitterOption {
+	return JitterOption(func(j *jitter) error {
+		if math.IsNaN(under) || under < 0 || under > 1 {
+			return fmt.Errorf("under must be between 0 and 1: %f", under)
+		}
+		if math.IsNaN(over) || math.IsInf(over, 0) || over < 0 {
+			return fmt.Errorf("over must be a non-negative real number: %f", over)
+		}
+		j.under = under
+		j.over = over
+		return nil
+	})
+}
+
+// JitterAbsolute option allows the consumer to define the maximum reduction
+// and increase applied to the duration delivered by the underlying BackOff,
+// as fixed amounts of time. These are added on top of any fractional or
+// percentage jitter, and the result is never reduced below zero.
+func JitterAbsolute(under time.Duration, over time.Duration) JitterOption {
+	return JitterOption(func(j *jitter) error {
+		if under < 0 || over < 0 {
+			return fmt.Errorf("absolute jitter must not be negative: %v, %v", under, over)
+		}
+		j.underAbs = under
+		j.overAbs = over
+		return nil
+	})
+}
+
+// JitterSafe is used to make sure a source of randomness supplied through
+// JitterRandomizer is only ever used by one goroutine at a time, which makes
+// the created BackOff concurrent-safe (as long as the underlying BackOff is,
+// too). The default source of randomness is always concurrent-safe.
+func JitterSafe(safe bool) JitterOption {
+	return JitterOption(func(j *jitter) error {
+		j.safe = safe
 		return nil
 	})
 }
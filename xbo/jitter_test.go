@@ -21,6 +21,7 @@
 package xbo
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -48,6 +49,14 @@ func TestNewJitterErrors(t *testing.T) {
 		JitterRandomizer(nil),
 		JitterUnder(101),
 		JitterOver(101),
+		JitterFraction(-0.1, 0),
+		JitterFraction(1.1, 0),
+		JitterFraction(math.NaN(), 0),
+		JitterFraction(0, -0.1),
+		JitterFraction(0, math.NaN()),
+		JitterFraction(0, math.Inf(1)),
+		JitterAbsolute(-time.Second, 0),
+		JitterAbsolute(0, -time.Second),
 	}
 
 	for _, opt := range opts {
@@ -221,3 +230,136 @@ func TestJitterRandomish(t *testing.T) {
 		t.Errorf("Did not expect so many matches!")
 	}
 }
+
+func TestJitterSafe(t *testing.T) {
+	base := time.Second * 10
+	bos := []BackOff{
+		// Our own source of randomness
+		clean(NewJitter(NewConstant(base),
+			JitterUnder(50),
+			JitterOver(50),
+			JitterSafe(true),
+		)),
+		// One that the consumer brought along
+		clean(NewJitter(NewLimit([]time.Duration{base, base, base}, true),
+			JitterUnder(50),
+			JitterRandomizer(rand.New(rand.NewSource(time.Now().UnixNano()))),
+			JitterSafe(true),
+		)),
+	}
+
+	for _, bo := range bos {
+		got, _ := hammer(t, bo, 200, 50, 11)
+		for _, dur := range got {
+			if dur < base/2 || dur > base*3/2 {
+				t.Errorf("out of range: %v", dur)
+			}
+		}
+	}
+}
+
+func TestJitterFractionAndAbsolute(t *testing.T) {
+	base := time.Second * 10
+	bo := NewConstant(base)
+	ms := time.Millisecond
+
+	testCases := []struct {
+		opts []JitterOption
+		min  time.Duration
+		max  time.Duration
+	}{
+		{
+			[]JitterOption{JitterFraction(0.025, 0.025)},
+			base - 250*ms,
+			base + 250*ms,
+		},
+		{
+			[]JitterOption{JitterFraction(0, 3.0)},
+			base,
+			base * 4,
+		},
+		{
+			[]JitterOption{JitterAbsolute(0, 250*ms)},
+			base,
+			base + 250*ms,
+		},
+		{
+			// Absolute jitter adds on top of fractional jitter
+			[]JitterOption{JitterUnder(10), JitterAbsolute(ms, ms)},
+			base - time.Second - ms,
+			base + ms,
+		},
+		{
+			// But never takes us below zero
+			[]JitterOption{JitterFraction(0.5, 0), JitterAbsolute(time.Hour, 0)},
+			0,
+			base,
+		},
+	}
+
+	for _, tc := range testCases {
+		ends := []struct {
+			r        JitterRand
+			expected time.Duration
+		}{
+			{Bottom(), tc.min},
+			{Topper(), tc.max},
+		}
+		for _, end := range ends {
+			opts := append([]JitterOption{JitterRandomizer(end.r)}, tc.opts...)
+			j, err := NewJitter(bo, opts...)
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			dur, err := j.Next(false)
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+			if dur != end.expected {
+				t.Errorf("expected %v: %v", end.expected, dur)
+			}
+		}
+	}
+
+	// Absolute jitter applies even to a zero duration
+	j, err := NewJitter(NewZero(), JitterAbsolute(0, ms), JitterRandomizer(Topper()))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	dur, err := j.Next(false)
+	if dur != ms {
+		t.Errorf("expected %v: %v", ms, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
+
+func TestJitterOverflow(t *testing.T) {
+	huge := time.Duration(math.MaxInt64 / 2)
+	inputs := []struct {
+		dur  time.Duration
+		opts []JitterOption
+	}{
+		{huge, []JitterOption{JitterFraction(0, 3.0)}},
+		{huge, []JitterOption{JitterOver(100), JitterAbsolute(0, huge)}},
+		{time.Duration(math.MaxInt64), []JitterOption{JitterFraction(1.0, 1.0)}},
+	}
+
+	for _, input := range inputs {
+		for _, r := range []JitterRand{Bottom(), Topper()} {
+			opts := append([]JitterOption{JitterRandomizer(r)}, input.opts...)
+			j, err := NewJitter(NewConstant(input.dur), opts...)
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			dur, err := j.Next(false)
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+			if dur < 0 {
+				t.Errorf("overflowed: %v", dur)
+			}
+		}
+	}
+}
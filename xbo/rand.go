@@ -0,0 +1,130 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	mrand "math/rand"
+	randv2 "math/rand/v2"
+	"sync"
+	"time"
+)
+
+// JitterRand isolates the one function that we need from the
+// math/rand.Rand type. The user may choose to implement their own
+// randomization source, as long as it fulfills this interface.
+type JitterRand interface {
+	Int63n(n int64) int64
+}
+
+// NewLockedRand makes any JitterRand safe for concurrent use, by making sure
+// only one goroutine at a time can use it. This is the simplest option, and
+// keeps a seeded source reproducible, but goroutines will contend for the
+// lock under heavy load.
+func NewLockedRand(r JitterRand) JitterRand {
+	if l, ok := r.(*lockedRand); ok {
+		// No need to lock twice
+		return l
+	}
+	return &lockedRand{r: r}
+}
+
+type lockedRand struct {
+	mu sync.Mutex
+	r  JitterRand
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int63n(n)
+}
+
+// NewPooledRand creates a JitterRand that is safe for concurrent use, and
+// avoids contention by keeping a pool of randomly-seeded sources, so that
+// goroutines running in parallel will generally each be using their own.
+//
+// This is the default source of randomness for NewJitter.
+func NewPooledRand() JitterRand {
+	return &pooledRand{pool: sync.Pool{
+		New: func() interface{} {
+			return randomlySeededRand()
+		},
+	}}
+}
+
+type pooledRand struct {
+	pool sync.Pool
+}
+
+func (p *pooledRand) Int63n(n int64) int64 {
+	r := p.pool.Get().(*mrand.Rand)
+	defer p.pool.Put(r)
+	return r.Int63n(n)
+}
+
+// I've seen other utilities just use time.Now().UnixNano() to seed
+// their random, but here we are using a randomly-generated seed,
+// because the whole point of adding jitter is to reduce likelihood of
+// accidental synchronization. (We only fall back to the time if the
+// system's secure random number generator fails us.)
+func randomlySeededRand() *mrand.Rand {
+	seed := time.Now().UnixNano()
+	b := make([]byte, 8)
+	_, err := crand.Reader.Read(b)
+	if err == nil {
+		seed = int64(binary.BigEndian.Uint64(b))
+	}
+	return mrand.New(mrand.NewSource(seed))
+}
+
+// NewChaCha8Rand creates a JitterRand backed by the math/rand/v2 ChaCha8
+// generator, which is safe for concurrent use. The same seed always gives the
+// same sequence (as long as it is only used by one goroutine at a time).
+func NewChaCha8Rand(seed [32]byte) JitterRand {
+	return &chacha8Rand{r: randv2.New(randv2.NewChaCha8(seed))}
+}
+
+type chacha8Rand struct {
+	mu sync.Mutex
+	r  *randv2.Rand
+}
+
+func (c *chacha8Rand) Int63n(n int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.r.Int64N(n)
+}
+
+// NewRuntimeRand creates a JitterRand backed by the top-level functions of
+// math/rand/v2, which draw from per-thread ChaCha8 generators seeded by the
+// runtime. It is safe for concurrent use without any locking, but it cannot
+// be seeded.
+func NewRuntimeRand() JitterRand {
+	return runtimeRand{}
+}
+
+type runtimeRand struct{}
+
+func (runtimeRand) Int63n(n int64) int64 {
+	return randv2.Int64N(n)
+}
@@ -0,0 +1,141 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// checkRand makes sure the JitterRand stays in range when used from many
+// goroutines at once
+func checkRand(t *testing.T, r JitterRand) {
+	bo := clean(NewJitter(NewConstant(time.Second),
+		JitterUnder(50),
+		JitterOver(50),
+		JitterRandomizer(r),
+	))
+	got, _ := hammer(t, bo, 200, 50, 0)
+	if len(got) != 200*50 {
+		t.Errorf("expected %d: %d", 200*50, len(got))
+	}
+
+	distinct := map[time.Duration]bool{}
+	for _, dur := range got {
+		if dur < time.Second/2 || dur > time.Second*3/2 {
+			t.Errorf("out of range: %v", dur)
+		}
+		distinct[dur] = true
+	}
+	if len(distinct) < len(got)/2 {
+		t.Errorf("not very random: %d of %d", len(distinct), len(got))
+	}
+}
+
+func TestLockedRand(t *testing.T) {
+	checkRand(t, NewLockedRand(rand.New(rand.NewSource(time.Now().UnixNano()))))
+
+	// Locking is still deterministic, given the same seed
+	seed := time.Now().UnixNano()
+	r1 := NewLockedRand(rand.New(rand.NewSource(seed)))
+	r2 := rand.New(rand.NewSource(seed))
+	for ix := 0; ix < 100; ix++ {
+		n1, n2 := r1.Int63n(1000), r2.Int63n(1000)
+		if n1 != n2 {
+			t.Errorf("broken determinism: %d vs %d", n1, n2)
+		}
+	}
+
+	// And we don't bother locking twice
+	if NewLockedRand(r1) != r1 {
+		t.Errorf("expected the same JitterRand back")
+	}
+}
+
+func TestPooledRand(t *testing.T) {
+	checkRand(t, NewPooledRand())
+}
+
+func TestChaCha8Rand(t *testing.T) {
+	seed := [32]byte{1, 2, 3}
+	checkRand(t, NewChaCha8Rand(seed))
+
+	// The same seed gives the same sequence
+	r1, r2 := NewChaCha8Rand(seed), NewChaCha8Rand(seed)
+	for ix := 0; ix < 100; ix++ {
+		n1, n2 := r1.Int63n(1000), r2.Int63n(1000)
+		if n1 != n2 {
+			t.Errorf("broken determinism: %d vs %d", n1, n2)
+		}
+	}
+}
+
+func TestRuntimeRand(t *testing.T) {
+	checkRand(t, NewRuntimeRand())
+}
+
+func TestJitterSafeByDefault(t *testing.T) {
+	j := clean(NewJitter(NewZero(), JitterOver(10))).(*jitter)
+	if _, ok := j.r.(*pooledRand); !ok {
+		t.Errorf("expected pooled randomness: %T", j.r)
+	}
+
+	// When asked for safety, bring-your-own randomness gets locked
+	j = clean(NewJitter(NewZero(),
+		JitterOver(10),
+		JitterRandomizer(rand.New(rand.NewSource(1))),
+		JitterSafe(true),
+	)).(*jitter)
+	if _, ok := j.r.(*lockedRand); !ok {
+		t.Errorf("expected locked randomness: %T", j.r)
+	}
+}
+
+func benchmarkRand(b *testing.B, r JitterRand) {
+	bo := clean(NewJitter(NewConstant(time.Second),
+		JitterUnder(50),
+		JitterOver(50),
+		JitterRandomizer(r),
+	))
+	b.SetParallelism(64)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bo.Next(false)
+		}
+	})
+}
+
+func BenchmarkLockedRand(b *testing.B) {
+	benchmarkRand(b, NewLockedRand(rand.New(rand.NewSource(1))))
+}
+
+func BenchmarkPooledRand(b *testing.B) {
+	benchmarkRand(b, NewPooledRand())
+}
+
+func BenchmarkChaCha8Rand(b *testing.B) {
+	benchmarkRand(b, NewChaCha8Rand([32]byte{}))
+}
+
+func BenchmarkRuntimeRand(b *testing.B) {
+	benchmarkRand(b, NewRuntimeRand())
+}
@@ -0,0 +1,269 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReplayMismatch is the sentinel error returned by a Replay BackOff when
+// it is not driven with the same pattern of resets and advances that was
+// recorded, or when it is asked for more than was recorded.
+var ErrReplayMismatch = fmt.Errorf("replay does not match recording")
+
+// recordingHeader is the first line of the text format, so that we can
+// evolve the format later without breaking old recordings.
+const recordingHeader = "xbo-recording v1"
+
+// Event is a single recorded call to the Next method of a BackOff.
+type Event struct {
+	Reset bool
+	Delay time.Duration
+	Err   error
+}
+
+// String renders the Event in the same form used by the text format.
+func (e Event) String() string {
+	op := "next"
+	if e.Reset {
+		op = "reset"
+	}
+	var stop *StopError
+	switch {
+	case e.Err == nil:
+		return op + " " + e.Delay.String()
+	case errors.As(e.Err, &stop):
+		return fmt.Sprintf("%s stop %s %s %d %v", op,
+			strconv.Quote(string(stop.Reason)), strconv.Quote(stop.Source),
+			stop.Attempts, stop.Elapsed)
+	case errors.Is(e.Err, ErrStop):
+		return op + " stop"
+	}
+	return op + " error " + strconv.Quote(e.Err.Error())
+}
+
+// Recorder is a BackOff decorator that remembers every call made to it, and
+// what the underlying BackOff answered. It is safe for concurrent use.
+type Recorder struct {
+	bo     BackOff
+	mu     sync.Mutex
+	events []Event
+}
+
+// Record creates a Recorder around the underlying BackOff.
+func Record(bo BackOff) *Recorder {
+	return &Recorder{bo: bo}
+}
+
+// Next conforms to the BackOff interface
+func (r *Recorder) Next(reset bool) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dur, err := r.bo.Next(reset)
+	r.events = append(r.events, Event{Reset: reset, Delay: dur, Err: err})
+	return dur, err
+}
+
+func (r *Recorder) shadow(e estimate, now sinceFunc) (BackOff, error) {
+	return shadowOf(r.bo, e, now)
+}
+
+// Events returns a copy of everything recorded so far.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]Event, len(r.events))
+	copy(result, r.events)
+	return result
+}
+
+// WriteTo writes everything recorded so far in a stable, line-oriented text
+// format that can be read back in with ParseRecording. A StopError is
+// written with its Reason, Source, Attempts and Elapsed, so it comes back
+// the same.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	lines := []string{recordingHeader}
+	for _, e := range r.Events() {
+		lines = append(lines, e.String())
+	}
+	for _, line := range lines {
+		n, err := io.WriteString(w, line+"\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ParseRecording reads back in the text format produced by Recorder.WriteTo.
+// Blank lines and lines starting with "#" are ignored. A bare "stop", as
+// written by older versions, is read as a StopError from the Replay.
+func ParseRecording(rd io.Reader) ([]Event, error) {
+	var result []Event
+	scanner := bufio.NewScanner(rd)
+	header := false
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !header {
+			if line != recordingHeader {
+				return nil, fmt.Errorf("line %d: unrecognized header: %q", lineNo, line)
+			}
+			header = true
+			continue
+		}
+		e, err := parseEvent(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		result = append(result, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("missing header")
+	}
+	return result, nil
+}
+
+func parseEvent(line string) (Event, error) {
+	var e Event
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) != 2 {
+		return e, fmt.Errorf("malformed event: %q", line)
+	}
+
+	switch parts[0] {
+	case "next":
+	case "reset":
+		e.Reset = true
+	default:
+		return e, fmt.Errorf("unrecognized operation: %q", parts[0])
+	}
+
+	result := parts[1]
+	switch {
+	case result == "stop":
+		e.Err = &StopError{Reason: ReasonStopped, Source: "Replay"}
+	case strings.HasPrefix(result, "stop "):
+		stop, err := parseStop(strings.TrimPrefix(result, "stop "))
+		if err != nil {
+			return e, fmt.Errorf("malformed stop: %q", result)
+		}
+		e.Err = stop
+	case strings.HasPrefix(result, "error "):
+		msg, err := strconv.Unquote(strings.TrimPrefix(result, "error "))
+		if err != nil {
+			return e, fmt.Errorf("malformed error: %q", result)
+		}
+		e.Err = recordedError(msg)
+	default:
+		dur, err := time.ParseDuration(result)
+		if err != nil {
+			return e, err
+		}
+		e.Delay = dur
+	}
+	return e, nil
+}
+
+// parseStop reads back the details of a StopError, written as its quoted
+// Reason and Source, then its Attempts and Elapsed
+func parseStop(details string) (*StopError, error) {
+	var quoted [2]string
+	for ix := range quoted {
+		q, err := strconv.QuotedPrefix(details)
+		if err != nil {
+			return nil, err
+		}
+		if quoted[ix], err = strconv.Unquote(q); err != nil {
+			return nil, err
+		}
+		details = strings.TrimPrefix(details[len(q):], " ")
+	}
+
+	fields := strings.Fields(details)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected attempts and elapsed")
+	}
+	attempts, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	elapsed, err := time.ParseDuration(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return &StopError{
+		Reason:   Reason(quoted[0]),
+		Source:   quoted[1],
+		Attempts: attempts,
+		Elapsed:  elapsed,
+	}, nil
+}
+
+// recordedError gives back our own sentinels where we can, so that
+// comparisons in the consumer's code still work during a replay.
+func recordedError(msg string) error {
+	for _, sentinel := range []error{ErrStop, ErrLowBound} {
+		if msg == sentinel.Error() {
+			return sentinel
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// Replay creates a BackOff that plays back recorded Events, in order. If the
+// consumer calls for a reset where the recording has an advance (or vice
+// versa), or calls more times than were recorded, the Replay fails loudly by
+// returning ErrReplayMismatch, and keeps doing so from then on.
+func Replay(events []Event) BackOff {
+	var mu sync.Mutex
+	position := 0
+	failed := false
+	return BackOffFunc(func(reset bool) (time.Duration, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failed || position >= len(events) || events[position].Reset != reset {
+			failed = true
+			return ZeroDuration, ErrReplayMismatch
+		}
+
+		e := events[position]
+		position++
+		return e.Delay, e.Err
+	})
+}
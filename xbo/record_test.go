@@ -0,0 +1,171 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	under := Floor(NewLimit([]time.Duration{
+		time.Millisecond * 100, time.Millisecond * 1500,
+	}, false), time.Millisecond*500)
+	rec := Record(under)
+
+	resets := []bool{true, false, false, false, true, false}
+	var expected []Event
+	for _, reset := range resets {
+		dur, err := rec.Next(reset)
+		expected = append(expected, Event{Reset: reset, Delay: dur, Err: err})
+	}
+
+	var buf bytes.Buffer
+	n, err := rec.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d: %d", buf.Len(), n)
+	}
+
+	text := `xbo-recording v1
+reset 0s
+next 500ms
+next 1.5s
+next stop "sequence exhausted" "NewLimit" 2 0s
+reset 0s
+next 500ms
+`
+	if buf.String() != text {
+		t.Errorf("expected %q: %q", text, buf.String())
+	}
+
+	events, err := ParseRecording(&buf)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d: %d", len(expected), len(events))
+	}
+
+	bo := Replay(events)
+	for ix, e := range expected {
+		if !reflect.DeepEqual(events[ix], e) {
+			t.Errorf("expected %v: %v", e, events[ix])
+		}
+		dur, err := bo.Next(e.Reset)
+		if dur != e.Delay {
+			t.Errorf("expected %v: %v", e.Delay, dur)
+		}
+		if !reflect.DeepEqual(err, e.Err) {
+			t.Errorf("expected %v: %v", e.Err, err)
+		}
+	}
+
+	// We've run off the end of the recording
+	_, err = bo.Next(false)
+	if err != ErrReplayMismatch {
+		t.Errorf("expected %v: %v", ErrReplayMismatch, err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	bo := Replay([]Event{
+		{Delay: time.Second},
+		{Delay: time.Second},
+	})
+
+	// Recording says advance, but we're resetting
+	_, err := bo.Next(true)
+	if err != ErrReplayMismatch {
+		t.Errorf("expected %v: %v", ErrReplayMismatch, err)
+	}
+
+	// And once it's failed, it stays failed
+	_, err = bo.Next(false)
+	if err != ErrReplayMismatch {
+		t.Errorf("expected %v: %v", ErrReplayMismatch, err)
+	}
+}
+
+func TestParseRecording(t *testing.T) {
+	text := `# captured from production
+xbo-recording v1
+
+next 1m0s
+next error "boundary condition too low"
+next error "something \"else\""
+next stop
+reset stop "too many attempts" "MaxAttempts" 3 1.5s
+`
+	events, err := ParseRecording(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5: %d", len(events))
+	}
+	if events[0].Delay != time.Minute {
+		t.Errorf("expected %v: %v", time.Minute, events[0].Delay)
+	}
+	if events[1].Err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, events[1].Err)
+	}
+	if msg := events[2].Err.Error(); msg != `something "else"` {
+		t.Errorf("unexpected: %s", msg)
+	}
+	stops := []*StopError{
+		{Reason: ReasonStopped, Source: "Replay"},
+		{Reason: ReasonAttempts, Source: "MaxAttempts", Attempts: 3, Elapsed: time.Millisecond * 1500},
+	}
+	for ix, stop := range stops {
+		if !reflect.DeepEqual(events[ix+3].Err, stop) {
+			t.Errorf("expected %v: %v", stop, events[ix+3].Err)
+		}
+	}
+
+	bads := []string{
+		"",
+		"next 1s",
+		"xbo-recording v2\nnext 1s",
+		"xbo-recording v1\nnext",
+		"xbo-recording v1\nskip 1s",
+		"xbo-recording v1\nnext soon",
+		"xbo-recording v1\nnext error unquoted",
+		"xbo-recording v1\nnext stop unquoted",
+		"xbo-recording v1\nnext stop \"stopped\" \"Loop\"",
+		"xbo-recording v1\nnext stop \"stopped\" \"Loop\" many 0s",
+		"xbo-recording v1\nnext stop \"stopped\" \"Loop\" 1 soon",
+	}
+	for _, bad := range bads {
+		events, err := ParseRecording(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("expected error: %q", bad)
+		}
+		if events != nil {
+			t.Errorf("expected nil: %v", events)
+		}
+	}
+}
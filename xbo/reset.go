@@ -0,0 +1,114 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResetAfter is a BackOff decorator that resets the underlying BackOff on
+// the consumer's behalf, if it has gone quiet: that is, if no call to Next
+// has come within the quiet window after the end of the last duration it
+// handed out (or after it said to stop). This suits consumers that only
+// ever report failures, so that failures hours apart aren't treated as one
+// long run.
+//
+// Use BoundSafe to make it concurrent-safe (as long as the underlying
+// BackOff is, too), and BoundClock to control how the time is told.
+func ResetAfter(bo BackOff, quiet time.Duration, options ...BoundOption) (BackOff, error) {
+	b, err := newBounded(bo, options)
+	if err != nil {
+		return nil, err
+	}
+	if quiet < 1 {
+		return nil, ErrLowBound
+	}
+	now := b.now
+	origin := now()
+	return resetAfter(bo, quiet, b.safe, func() time.Duration {
+		return now().Sub(origin)
+	}), nil
+}
+
+// resetAfter does the work of ResetAfter, telling the time with the
+// sinceFunc
+func resetAfter(bo BackOff, quiet time.Duration, safe bool, since sinceFunc) BackOff {
+	var mu sync.Mutex
+	called := false
+	var until time.Duration
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		if safe {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+
+		now := since()
+		if !reset && called && now-until > quiet {
+			// It's been a while, so start over
+			_, err := bo.Next(true)
+			if err != nil {
+				return ZeroDuration, err
+			}
+		}
+
+		dur, err := bo.Next(reset)
+		called = true
+		until = addDurations(now, dur)
+		return dur, err
+	})
+	return analyzable{f, func(e estimate, now sinceFunc) (BackOff, error) {
+		shadow, err := shadowOf(bo, e, now)
+		if err != nil {
+			return nil, err
+		}
+		return resetAfter(shadow, quiet, false, now), nil
+	}}
+}
+
+// RequireSuccesses is a BackOff decorator that only passes a reset along to
+// the underlying BackOff once it has seen k resets (i.e. successes) in a
+// row, without any other calls to Next (i.e. failures) in between. Until
+// then, resets are answered with ZeroDuration, and the underlying BackOff
+// carries on from where it was, so it only relaxes once whatever it is
+// protecting is healthy again. A k of 0 or 1 passes along every reset.
+//
+// The streak of successes is tracked atomically, so this is concurrent-safe
+// (as long as the underlying BackOff is, too).
+func RequireSuccesses(bo BackOff, k uint32) BackOff {
+	var streak uint32
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		if !reset {
+			atomic.StoreUint32(&streak, 0)
+			return bo.Next(reset)
+		}
+
+		// Don't let the streak wrap around
+		if atomic.LoadUint32(&streak) >= k || atomic.AddUint32(&streak, 1) >= k {
+			return bo.Next(reset)
+		}
+		return ZeroDuration, nil
+	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return RequireSuccesses(shadows[0], k)
+	})
+}
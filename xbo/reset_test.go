@@ -0,0 +1,192 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResetAfterErrors(t *testing.T) {
+	inputs := []struct {
+		bo      BackOff
+		quiet   time.Duration
+		options []BoundOption
+	}{
+		{nil, time.Second, nil},
+		{NewZero(), 0, nil},
+		{NewZero(), time.Second, []BoundOption{BoundClock(nil)}},
+	}
+	for ix, input := range inputs {
+		bo, err := ResetAfter(input.bo, input.quiet, input.options...)
+		if err == nil {
+			t.Errorf("%d: expected error", ix)
+		}
+		if bo != nil {
+			t.Errorf("%d: expected nil: %v", ix, bo)
+		}
+	}
+}
+
+func TestResetAfter(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	exp, err := NewExponential(time.Second, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo, err := ResetAfter(exp, time.Minute, BoundClock(clock), BoundSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	check := func(expected time.Duration) {
+		t.Helper()
+		dur, err := bo.Next(false)
+		if dur != expected {
+			t.Errorf("expected %v: %v", expected, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+
+	// Failures in quick succession keep backing off
+	check(time.Second)
+	now = now.Add(time.Second)
+	check(time.Second * 2)
+
+	// Going quiet for a while (after the last wait is over) resets
+	now = now.Add(time.Second*2 + time.Minute + 1)
+	check(time.Second)
+
+	// Long waits don't count towards going quiet
+	for ix := 1; ix < 8; ix++ {
+		now = now.Add(time.Second << uint(ix-1))
+		check(time.Second << uint(ix))
+	}
+	now = now.Add(time.Second<<7 + time.Minute)
+	check(time.Second << 8)
+}
+
+func TestResetAfterStop(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	bo, err := ResetAfter(MaxAttempts(NewConstant(time.Second), 1, false), time.Minute, BoundClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo.Next(false)
+	_, err = bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	// Still stopped, until it's been quiet for a while
+	now = now.Add(time.Minute)
+	_, err = bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+	now = now.Add(time.Minute + 1)
+	dur, err := bo.Next(false)
+	if dur != time.Second {
+		t.Errorf("expected %v: %v", time.Second, dur)
+	}
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+}
+
+func TestResetAfterSafe(t *testing.T) {
+	bo, err := ResetAfter(NewLoop([]time.Duration{1, 2, 3, 4, 5}, true), time.Hour, BoundSafe(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	durs, stops := hammer(t, bo, 8, 500, 0)
+	if stops != 0 {
+		t.Errorf("unexpected stops: %d", stops)
+	}
+	if len(durs) != 8*500 {
+		t.Errorf("expected %d: %d", 8*500, len(durs))
+	}
+}
+
+func TestRequireSuccesses(t *testing.T) {
+	s := time.Second
+	exp, err := NewExponential(s, 1.0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	bo := RequireSuccesses(MaxAttempts(exp, 5, false), 3)
+
+	checkNext(t, bo, s, s*2, s*4)
+
+	// Not enough successes in a row
+	for ix := 0; ix < 2; ix++ {
+		dur, err := bo.Next(true)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+	checkNext(t, bo, s*8)
+
+	// The failure started the count again
+	bo.Next(true)
+	bo.Next(true)
+	checkNext(t, bo, s*16)
+	_, err = bo.Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	// Now the dependency is stable again
+	for ix := 0; ix < 5; ix++ {
+		bo.Next(true)
+	}
+	checkNext(t, bo, s, s*2)
+}
+
+func TestRequireSuccessesOne(t *testing.T) {
+	for _, k := range []uint32{0, 1} {
+		bo := RequireSuccesses(NewLimit([]time.Duration{time.Second, time.Minute}, false), k)
+		checkNext(t, bo, time.Second)
+		bo.Next(true)
+		checkNext(t, bo, time.Second)
+	}
+}
+
+func TestRequireSuccessesSafe(t *testing.T) {
+	bo := RequireSuccesses(NewLoop([]time.Duration{1, 2, 3, 4, 5}, true), 2)
+	durs, stops := hammer(t, bo, 8, 500, 3)
+	if stops != 0 {
+		t.Errorf("unexpected stops: %d", stops)
+	}
+	if len(durs) == 0 {
+		t.Errorf("expected durations")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Retry calls op until it succeeds, using the Waiter to wait between
+// attempts, and returns nil once it does. The underlying BackOff is reset
+// before the first attempt.
+//
+// If the BackOff says to stop, the error from the last attempt is returned.
+// Any other reason for giving up (e.g. the Context being cancelled) is
+// returned instead. Use RetryHistory to get an error that keeps track of
+// every failed attempt, as well as why retrying ended.
+func (w Waiter) Retry(ctx context.Context, op func(context.Context) error, options ...RetryOption) error {
+	r := &retry{}
+	for _, opt := range options {
+		err := opt(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.wait(ctx, true); err != nil {
+		return err
+	}
+
+	var history *RetryError
+	if r.limit > 0 {
+		history = &RetryError{}
+	}
+
+	for attempt := 1; ; attempt++ {
+		at := time.Now()
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+
+		delay, werr := w.wait(ctx, false)
+		if history != nil {
+			history.add(Failure{Attempt: attempt, At: at, Delay: delay, Err: err}, r.limit)
+		}
+		if werr == nil {
+			continue
+		}
+
+		if history != nil {
+			history.Err = werr
+			return history
+		}
+		if errors.Is(werr, ErrStop) {
+			return err
+		}
+		return werr
+	}
+}
+
+// RetryOption declares the functional options for changing the behavior of
+// Waiter.Retry.
+type RetryOption func(*retry) error
+
+type retry struct {
+	limit int
+}
+
+// RetryHistory makes Retry return a RetryError when it gives up, which
+// holds on to (at most) the limit most recent failed attempts.
+func RetryHistory(limit int) RetryOption {
+	return RetryOption(func(r *retry) error {
+		if limit < 1 {
+			return fmt.Errorf("history limit must be positive: %d", limit)
+		}
+		r.limit = limit
+		return nil
+	})
+}
+
+// Failure is a single failed attempt made by Retry.
+type Failure struct {
+	// Attempt counts up from 1
+	Attempt int
+	// At is when the attempt started
+	At time.Time
+	// Delay is how long Retry waited after the attempt (zero for the
+	// last one)
+	Delay time.Duration
+	// Err is what the attempt returned
+	Err error
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("attempt %d at %s (waited %v): %v",
+		f.Attempt, f.At.Format(time.RFC3339Nano), f.Delay, f.Err)
+}
+
+// RetryError is returned by Retry (when using RetryHistory) once it gives
+// up. It unwraps to the reason that retrying ended as well as to the error
+// from every failed attempt it kept, so errors.Is and errors.As can be used
+// to look for any of them.
+type RetryError struct {
+	// Failures holds the most recent failed attempts, oldest first
+	Failures []Failure
+	// Dropped is how many earlier failed attempts were not kept
+	Dropped int
+	// Err is why retrying ended, e.g. a StopError, or the error from the
+	// Context
+	Err error
+}
+
+func (e *RetryError) add(f Failure, limit int) {
+	if len(e.Failures) >= limit {
+		e.Failures = append(e.Failures[:0], e.Failures[1:]...)
+		e.Dropped++
+	}
+	e.Failures = append(e.Failures, f)
+}
+
+func (e *RetryError) Error() string {
+	attempts := e.Dropped + len(e.Failures)
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("gave up after %d attempts: %v", attempts, e.Err)
+	}
+	last := e.Failures[len(e.Failures)-1]
+	return fmt.Sprintf("gave up after %d attempts: %v: %v", attempts, e.Err, last.Err)
+}
+
+// Unwrap gives the reason that retrying ended, followed by the errors from
+// the failed attempts (most recent first).
+func (e *RetryError) Unwrap() []error {
+	result := []error{e.Err}
+	for ix := len(e.Failures) - 1; ix >= 0; ix-- {
+		result = append(result, e.Failures[ix].Err)
+	}
+	return result
+}
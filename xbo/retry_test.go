@@ -0,0 +1,180 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type attemptError struct {
+	attempt int
+}
+
+func (e attemptError) Error() string {
+	return fmt.Sprintf("attempt %d failed", e.attempt)
+}
+
+func failing(calls *int, succeedOn int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		*calls++
+		if *calls == succeedOn {
+			return nil
+		}
+		return attemptError{*calls}
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	w, err := NewWaiter(NewConstant(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	calls := 0
+	err = w.Retry(context.Background(), failing(&calls, 3), RetryHistory(5))
+	if err != nil {
+		t.Errorf("unexpected: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected %d: %d", 3, calls)
+	}
+}
+
+func TestRetryLastError(t *testing.T) {
+	w, err := NewWaiter(MaxAttempts(NewConstant(time.Millisecond), 3, false))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	// Without history, we just hear about the last attempt
+	calls := 0
+	err = w.Retry(context.Background(), failing(&calls, 0))
+	if err != (attemptError{4}) {
+		t.Errorf("expected %v: %v", attemptError{4}, err)
+	}
+}
+
+func TestRetryHistory(t *testing.T) {
+	w, err := NewWaiter(MaxAttempts(NewConstant(time.Millisecond), 5, false))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	start := time.Now()
+	calls := 0
+	err = w.Retry(context.Background(), failing(&calls, 0), RetryHistory(4))
+
+	var history *RetryError
+	if !errors.As(err, &history) {
+		t.Fatalf("expected RetryError: %v", err)
+	}
+
+	// Only the most recent attempts are kept
+	if history.Dropped != 2 {
+		t.Errorf("expected %d: %d", 2, history.Dropped)
+	}
+	if len(history.Failures) != 4 {
+		t.Fatalf("expected %d: %d", 4, len(history.Failures))
+	}
+	for ix, f := range history.Failures {
+		attempt := ix + 3
+		if f.Attempt != attempt {
+			t.Errorf("expected %d: %d", attempt, f.Attempt)
+		}
+		if f.Err != (attemptError{attempt}) {
+			t.Errorf("expected %v: %v", attemptError{attempt}, f.Err)
+		}
+		if f.At.Before(start) {
+			t.Errorf("expected after %v: %v", start, f.At)
+		}
+		start = f.At
+
+		delay := time.Millisecond
+		if attempt == 6 {
+			delay = ZeroDuration
+		}
+		if f.Delay != delay {
+			t.Errorf("expected %v: %v", delay, f.Delay)
+		}
+	}
+
+	// Both why we stopped, and what went wrong along the way, are there
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+	if !errors.Is(err, attemptError{3}) || !errors.Is(err, attemptError{6}) {
+		t.Errorf("expected attempt errors: %v", err)
+	}
+	if errors.Is(err, attemptError{1}) {
+		t.Errorf("expected dropped: %v", err)
+	}
+	var last attemptError
+	if !errors.As(err, &last) || last.attempt != 6 {
+		t.Errorf("expected %v: %v", attemptError{6}, last)
+	}
+
+	if !strings.HasPrefix(err.Error(), "gave up after 6 attempts") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	w, err := NewWaiter(NewConstant(time.Millisecond * 50))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	ctx, cxl := context.WithTimeout(context.Background(), time.Millisecond*120)
+	defer cxl()
+
+	calls := 0
+	err = w.Retry(ctx, failing(&calls, 0))
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v: %v", context.DeadlineExceeded, err)
+	}
+
+	calls = 0
+	ctx, cxl = context.WithTimeout(context.Background(), time.Millisecond*120)
+	defer cxl()
+	err = w.Retry(ctx, failing(&calls, 0), RetryHistory(10))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v: %v", context.DeadlineExceeded, err)
+	}
+	if !errors.Is(err, attemptError{calls}) {
+		t.Errorf("expected %v: %v", attemptError{calls}, err)
+	}
+}
+
+func TestRetryOptionErrors(t *testing.T) {
+	w, err := NewWaiter(NewZero())
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	err = w.Retry(context.Background(), failing(new(int), 1), RetryHistory(0))
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
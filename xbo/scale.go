@@ -0,0 +1,145 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Scale is a BackOff decorator that multiplies the durations delivered by
+// the underlying BackOff by whatever factor is returned from the factor
+// function. The function is consulted on every (non-reset) call, so the
+// factor may be changed at runtime; see Multiplier for a concurrent-safe
+// way of doing that.
+func Scale(bo BackOff, factor func() float64) BackOff {
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Find out what the underlying BackOff says
+		dur, err := bo.Next(reset)
+
+		// We only interject for non-reset, non-error conditions
+		if reset || err != nil {
+			return dur, err
+		}
+
+		f := factor()
+		if math.IsNaN(f) || math.IsInf(f, 0) || f < 0 {
+			return ZeroDuration, fmt.Errorf("invalid scale factor: %f", f)
+		}
+
+		return scaleDuration(dur, f), nil
+	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return Scale(shadows[0], factor)
+	})
+}
+
+// Offset is a BackOff decorator that adds whatever duration is returned from
+// the offset function to the durations delivered by the underlying BackOff.
+// The function is consulted on every (non-reset) call, so the offset may be
+// changed at runtime. Negative offsets are allowed, but the result will
+// never go below zero.
+func Offset(bo BackOff, offset func() time.Duration) BackOff {
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Find out what the underlying BackOff says
+		dur, err := bo.Next(reset)
+
+		// We only interject for non-reset, non-error conditions
+		if reset || err != nil {
+			return dur, err
+		}
+
+		result := addDurations(dur, offset())
+		if result < 0 {
+			return ZeroDuration, nil
+		}
+		return result, nil
+	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return Offset(shadows[0], offset)
+	})
+}
+
+// maxDuration is the largest duration we can represent; rather than
+// overflowing, calculations top out here.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// scaleDuration multiplies the (non-negative) duration by the (non-negative)
+// factor, rounding to the nearest nanosecond, and without overflowing.
+func scaleDuration(d time.Duration, f float64) time.Duration {
+	scaled := math.Round(float64(d) * f)
+	if scaled >= math.MaxInt64 {
+		return maxDuration
+	}
+	return time.Duration(scaled)
+}
+
+// addDurations adds the durations together without overflowing.
+func addDurations(a time.Duration, b time.Duration) time.Duration {
+	switch {
+	case b > 0 && a > maxDuration-b:
+		return maxDuration
+	case b < 0 && a < math.MinInt64-b:
+		return math.MinInt64
+	}
+	return a + b
+}
+
+// Multiplier holds a scaling factor that can be safely read and updated
+// from multiple goroutines. Its Factor method is intended to be handed to
+// Scale, so that operators can slow down (or speed up) every decorated
+// BackOff at runtime, without a redeploy.
+//
+// The zero value is a Multiplier with a factor of 1.0.
+type Multiplier struct {
+	// To make the zero value useful, we store the bits of the factor
+	// XOR'd with the bits of 1.0
+	bits uint64
+}
+
+var oneBits = math.Float64bits(1.0)
+
+// NewMultiplier creates a Multiplier with the given initial factor.
+func NewMultiplier(factor float64) (*Multiplier, error) {
+	m := &Multiplier{}
+	err := m.Set(factor)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Set changes the factor, which will be seen by every subsequent call
+// to Factor.
+func (m *Multiplier) Set(factor float64) error {
+	if math.IsNaN(factor) || math.IsInf(factor, 0) || factor < 0 {
+		return fmt.Errorf("factor must be a non-negative real number: %f", factor)
+	}
+	atomic.StoreUint64(&m.bits, math.Float64bits(factor)^oneBits)
+	return nil
+}
+
+// Factor returns the current factor.
+func (m *Multiplier) Factor() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&m.bits) ^ oneBits)
+}
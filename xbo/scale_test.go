@@ -0,0 +1,175 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScale(t *testing.T) {
+	m := &Multiplier{}
+	bo := Scale(NewConstant(time.Second), m.Factor)
+
+	testCases := []struct {
+		factor float64
+		dur    time.Duration
+	}{
+		{1.0, time.Second},
+		{4.0, time.Second * 4},
+		{0.5, time.Millisecond * 500},
+		{0.0, ZeroDuration},
+		{math.MaxFloat64, time.Duration(math.MaxInt64)},
+	}
+
+	for _, tc := range testCases {
+		err := m.Set(tc.factor)
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+
+		dur, err := bo.Next(false)
+		if dur != tc.dur {
+			t.Errorf("expected %v: %v", tc.dur, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+
+		// Also ensure the standard response for reset
+		dur, err = bo.Next(true)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+
+	// Errors from the underlying are left alone
+	_, err := Scale(NewStop(), m.Factor).Next(false)
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+
+	// And non-sensical factors are an error
+	factors := []float64{-1.0, math.NaN(), math.Inf(1), math.Inf(-1)}
+	for _, f := range factors {
+		factor := f
+		for _, d := range []time.Duration{ZeroDuration, time.Second} {
+			dur, err := Scale(NewConstant(d), func() float64 {
+				return factor
+			}).Next(false)
+			if err == nil {
+				t.Errorf("expected error for %f: %v", factor, dur)
+			}
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	var delta time.Duration
+	bo := Offset(NewConstant(time.Second), func() time.Duration {
+		return delta
+	})
+
+	testCases := []struct {
+		delta time.Duration
+		dur   time.Duration
+	}{
+		{0, time.Second},
+		{time.Millisecond * 250, time.Millisecond * 1250},
+		{-time.Millisecond * 250, time.Millisecond * 750},
+		{-time.Minute, ZeroDuration},
+		{time.Duration(math.MaxInt64), time.Duration(math.MaxInt64)},
+	}
+
+	for _, tc := range testCases {
+		delta = tc.delta
+		dur, err := bo.Next(false)
+		if dur != tc.dur {
+			t.Errorf("expected %v: %v", tc.dur, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+
+		// Also ensure the standard response for reset
+		dur, err = bo.Next(true)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	var m Multiplier
+	if f := m.Factor(); f != 1.0 {
+		t.Errorf("expected 1.0: %f", f)
+	}
+
+	factors := []float64{-1.0, math.NaN(), math.Inf(1)}
+	for _, f := range factors {
+		if err := m.Set(f); err == nil {
+			t.Errorf("expected error")
+		}
+		mm, err := NewMultiplier(f)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if mm != nil {
+			t.Errorf("expected nil: %v", mm)
+		}
+	}
+
+	mm, err := NewMultiplier(0)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if f := mm.Factor(); f != 0 {
+		t.Errorf("expected 0: %f", f)
+	}
+
+	// Ensure updates and reads from many goroutines are kosher
+	bo := Scale(NewConstant(time.Second), mm.Factor)
+	var wg sync.WaitGroup
+	for ix := 0; ix < 50; ix++ {
+		wg.Add(1)
+		go func(ix int) {
+			defer wg.Done()
+			for jx := 0; jx < 100; jx++ {
+				if jx%10 == 0 {
+					mm.Set(float64(ix))
+				}
+				if _, err := bo.Next(false); err != nil {
+					t.Errorf("unexpected: %v", err)
+				}
+			}
+		}(ix)
+	}
+	wg.Wait()
+}
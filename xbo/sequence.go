@@ -27,12 +27,12 @@ import (
 
 // NewLoop allows the client to define a discrete list of durations that
 // will be continually looped over, without end. Resets do put the iteration
-// back to the first item.
+// back to the first item (unless told otherwise by SequenceDecay).
 //
 // Misconfiguraiton (0-length slice) will create a Backoff that always
 // returns ErrStop (when not being reset).
-func NewLoop(durs []time.Duration, safe bool) BackOff {
-	return newSequence(durs, safe, true, false)
+func NewLoop(durs []time.Duration, safe bool, options ...SequenceOption) BackOff {
+	return newSequence(durs, safe, true, false, options)
 }
 
 // NewLimit allows the client to define a discrete list of durations that
@@ -41,8 +41,8 @@ func NewLoop(durs []time.Duration, safe bool) BackOff {
 //
 // Misconfiguraiton (0-length slice) will create a Backoff that always
 // returns ErrStop (when not being reset).
-func NewLimit(durs []time.Duration, safe bool) BackOff {
-	return newSequence(durs, safe, false, false)
+func NewLimit(durs []time.Duration, safe bool, options ...SequenceOption) BackOff {
+	return newSequence(durs, safe, false, false, options)
 }
 
 // NewEcho allows the client to define a discrete list of durations that
@@ -51,11 +51,27 @@ func NewLimit(durs []time.Duration, safe bool) BackOff {
 //
 // Misconfiguraiton (0-length slice) will create a Backoff that always
 // returns ErrStop (when not being reset).
-func NewEcho(durs []time.Duration, safe bool) BackOff {
-	return newSequence(durs, safe, false, true)
+func NewEcho(durs []time.Duration, safe bool, options ...SequenceOption) BackOff {
+	return newSequence(durs, safe, false, true, options)
 }
 
-func newSequence(durs []time.Duration, safe bool, loop bool, echo bool) BackOff {
+// SequenceOption declares the functional options for changing behavior on
+// the BackOffs created by NewLoop, NewLimit and NewEcho.
+type SequenceOption func(*sequence)
+
+type sequence struct {
+	decay Decay
+}
+
+// SequenceDecay makes resets take the BackOff back gradually, according to
+// the Decay, rather than all the way to the first item.
+func SequenceDecay(decay Decay) SequenceOption {
+	return SequenceOption(func(s *sequence) {
+		s.decay = decay
+	})
+}
+
+func newSequence(durs []time.Duration, safe bool, loop bool, echo bool, options []SequenceOption) BackOff {
 	// Since we are trying to protect some underlying resource, if the user
 	// specified an empty (nonsensical) slice, then default to stopping
 	// any retries
@@ -64,43 +80,77 @@ func newSequence(durs []time.Duration, safe bool, loop bool, echo bool) BackOff
 		return NewStop()
 	}
 
+	var s sequence
+	for _, opt := range options {
+		opt(&s)
+	}
+
+	// Where a reset takes us back to depends on where we have got to in
+	// the sequence (as the count keeps on going past the end)
+	rewind := func(count uint32) uint32 {
+		if loop {
+			count = count % size
+		} else if count > size {
+			count = size
+		}
+		return s.decay.apply(count)
+	}
+
 	count := uint32(0)
-	return BackOffFunc(func(reset bool) (time.Duration, error) {
+	var decide func() (Decision, error)
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
 		// Reset is pretty easy
 		if reset {
-			if safe {
-				atomic.StoreUint32(&count, 0)
-			} else {
-				count = 0
+			if !safe {
+				count = rewind(count)
+				return ZeroDuration, nil
+			}
+			for {
+				old := atomic.LoadUint32(&count)
+				if atomic.CompareAndSwapUint32(&count, old, rewind(old)) {
+					return ZeroDuration, nil
+				}
 			}
-			return ZeroDuration, nil
 		}
 
-		// After calculating the current duration we will increase the count
-		// for the next time around
-		defer func() {
-			next := count + 1
-			if safe {
-				atomic.AddUint32(&count, 1)
-			} else {
-				count = next
-			}
-		}()
+		d, err := decide()
+		return d.Delay, err
+	})
+	decide = func() (Decision, error) {
+		// Claim our position in the sequence, and move the count
+		// along for the next time around
+		var offset uint32
+		if safe {
+			offset = atomic.AddUint32(&count, 1) - 1
+		} else {
+			offset = count
+			count++
+		}
+		result := Decision{Attempt: uint64(offset) + 1}
 
-		offset := count
 		if loop {
-			offset = count % size
+			offset = offset % size
 		}
 
 		// Short-circuit if we're at the max size
 		if offset >= size {
 			if echo {
 				// Just echo the last entry
-				return durs[size-1], nil
+				result.Delay = durs[size-1]
+				return result, nil
+			}
+			result.Reason = ReasonExhausted
+			return result, &StopError{
+				Reason:   ReasonExhausted,
+				Source:   "NewLimit",
+				Attempts: uint64(size),
 			}
-			return ZeroDuration, ErrStop
 		}
 
-		return durs[offset], nil
-	})
+		result.Delay = durs[offset]
+		return result, nil
+	}
+	return counting{analyzable{f, func(estimate, sinceFunc) (BackOff, error) {
+		return newSequence(durs, false, loop, echo, options), nil
+	}}, decide}
 }
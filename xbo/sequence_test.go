@@ -21,6 +21,8 @@
 package xbo
 
 import (
+	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -104,7 +106,8 @@ func TestSequenceBackOffs(t *testing.T) {
 				if dur != xDur {
 					t.Errorf("expected %s: %s", xDur, dur)
 				}
-				if err != xErr {
+				// (They needn't agree on why they stopped)
+				if (err == nil) != (xErr == nil) || errors.Is(err, ErrStop) != errors.Is(xErr, ErrStop) {
 					t.Errorf("expected %v: %v", xErr, err)
 				}
 			}
@@ -122,3 +125,78 @@ func TestSequenceBackOffs(t *testing.T) {
 		}
 	}
 }
+
+// hammer calls Next on the BackOff from many goroutines at once (resetting
+// every so often, if asked), and gathers up every duration returned, as well
+// as how many times it was told to stop
+func hammer(t *testing.T, bo BackOff, goroutines int, calls int, resetEvery int) ([]time.Duration, int) {
+	var mu sync.Mutex
+	var durs []time.Duration
+	stops := 0
+
+	var wg sync.WaitGroup
+	for ix := 0; ix < goroutines; ix++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jx := 1; jx <= calls; jx++ {
+				reset := resetEvery > 0 && jx%resetEvery == 0
+				dur, err := bo.Next(reset)
+				if reset {
+					continue
+				}
+
+				mu.Lock()
+				switch {
+				case err == nil:
+					durs = append(durs, dur)
+				case errors.Is(err, ErrStop):
+					stops++
+				default:
+					t.Errorf("unexpected: %v", err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return durs, stops
+}
+
+func TestSequenceSafe(t *testing.T) {
+	var durs []time.Duration
+	for ix := 1; ix <= 100; ix++ {
+		durs = append(durs, time.Duration(ix))
+	}
+
+	// Every entry in the list should be handed out exactly once
+	limit := NewLimit(durs, true)
+	got, stops := hammer(t, limit, 200, 50, 0)
+	if len(got) != len(durs) {
+		t.Errorf("expected %d: %d", len(durs), len(got))
+	}
+	if stops != 200*50-len(durs) {
+		t.Errorf("expected %d: %d", 200*50-len(durs), stops)
+	}
+	seen := map[time.Duration]bool{}
+	for _, dur := range got {
+		if seen[dur] {
+			t.Errorf("duplicate: %v", dur)
+		}
+		seen[dur] = true
+	}
+
+	// Loops and echoes just need to stay in range, even with resets
+	bos := []BackOff{NewLoop(durs, true), NewEcho(durs, true)}
+	for _, bo := range bos {
+		got, stops := hammer(t, bo, 200, 50, 7)
+		if stops != 0 {
+			t.Errorf("expected 0: %d", stops)
+		}
+		for _, dur := range got {
+			if dur < 1 || dur > 100 {
+				t.Errorf("out of range: %v", dur)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import "time"
+
+// SpreadBy is a BackOff decorator that adds a stable offset, somewhere
+// within the window, to the durations delivered by the underlying BackOff.
+// The offset is derived from a hash of the identity given (e.g. a hostname or
+// agent ID), so each member of a fleet keeps its own slot within the window,
+// even across restarts.
+func SpreadBy(bo BackOff, id string, window time.Duration) BackOff {
+	offset := scaleDuration(window, identityFraction(id))
+	spread := Offset(bo, func() time.Duration { return offset })
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Check for non-sensical boundary condition
+		if window < 1 {
+			return ZeroDuration, ErrLowBound
+		}
+		return spread.Next(reset)
+	})
+	return decorating(f, []BackOff{bo}, func(shadows []BackOff) BackOff {
+		return SpreadBy(shadows[0], id, window)
+	})
+}
+
+// Spread is a BackOff decorator that, when reset, returns a random duration
+// within the window (inclusive), rather than the customary ZeroDuration.
+// When a whole fleet starts (or recovers) at the same moment, resetting
+// before the first attempt (e.g. with Waiter.Wait(ctx, true)) keeps them
+// from all making that first attempt at the same instant.
+//
+// If r is nil, a concurrent-safe source of randomness is used; otherwise it
+// is used as-is (see NewLockedRand).
+func Spread(bo BackOff, window time.Duration, r JitterRand) BackOff {
+	if r == nil {
+		r = NewPooledRand()
+	}
+	f := BackOffFunc(func(reset bool) (time.Duration, error) {
+		// Check for non-sensical boundary condition
+		if window < 1 {
+			return ZeroDuration, ErrLowBound
+		}
+
+		// Find out what the underlying BackOff says
+		dur, err := bo.Next(reset)
+
+		// We only interject for reset, non-error conditions
+		if err != nil || !reset {
+			return dur, err
+		}
+
+		// (Add one, because result range does not include the max number.)
+		span := int64(window)
+		if span < int64(maxDuration) {
+			span++
+		}
+		return time.Duration(r.Int63n(span)), nil
+	})
+	return analyzable{f, func(e estimate, now sinceFunc) (BackOff, error) {
+		shadow, err := shadowOf(bo, e, now)
+		if err != nil {
+			return nil, err
+		}
+		return Spread(shadow, window, e), nil
+	}}
+}
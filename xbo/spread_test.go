@@ -0,0 +1,178 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSpreadBy(t *testing.T) {
+	window := time.Second * 10
+
+	// The same identity always lands in the same spot
+	ids := []string{"agent-1", "agent-2", "agent-3"}
+	offsets := map[time.Duration]bool{}
+	for _, id := range ids {
+		bo := SpreadBy(NewConstant(time.Second), id, window)
+		again := SpreadBy(NewConstant(time.Second), id, window)
+
+		dur, err := bo.Next(false)
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+		if dur < time.Second || dur >= time.Second+window {
+			t.Errorf("out of range: %v", dur)
+		}
+		offsets[dur] = true
+
+		for ix := 0; ix < 3; ix++ {
+			other, err := again.Next(false)
+			if other != dur {
+				t.Errorf("expected %v: %v", dur, other)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+		}
+
+		// Resets are left alone
+		dur, err = bo.Next(true)
+		if dur != ZeroDuration {
+			t.Errorf("expected %v: %v", ZeroDuration, dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+	}
+
+	// But different identities are spread out
+	if len(offsets) != len(ids) {
+		t.Errorf("expected %d distinct offsets: %d", len(ids), len(offsets))
+	}
+
+	// Check for non-sensical boundary condition
+	_, err := SpreadBy(NewConstant(time.Second), "agent-1", 0).Next(false)
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+}
+
+func TestIdentityFraction(t *testing.T) {
+	// A fleet should be spread fairly evenly across the range
+	buckets := make([]int, 10)
+	for ix := 0; ix < 10000; ix++ {
+		f := identityFraction(fmt.Sprintf("host-%05d.example.com", ix))
+		if f < 0 || f >= 1 {
+			t.Fatalf("out of range: %f", f)
+		}
+		buckets[int(f*10)]++
+	}
+	for ix, count := range buckets {
+		if count < 800 || count > 1200 {
+			t.Errorf("bucket %d is lopsided: %d", ix, count)
+		}
+	}
+
+	// Even when the identities only differ at the very end
+	slots := make([]int, 100)
+	for ix := 0; ix < 1000; ix++ {
+		f := identityFraction(fmt.Sprintf("agent-%d", ix))
+		slots[int(f*100)]++
+	}
+	for ix, count := range slots {
+		if count == 0 || count > 25 {
+			t.Errorf("slot %d is lopsided: %d", ix, count)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	window := time.Second * 10
+	under := NewLimit([]time.Duration{time.Second}, false)
+
+	testCases := []struct {
+		r   JitterRand
+		dur time.Duration
+	}{
+		{Bottom(), ZeroDuration},
+		{Topper(), window},
+	}
+
+	for _, tc := range testCases {
+		bo := Spread(under, window, tc.r)
+
+		// Several cycles to prove reset works
+		for ix := 0; ix < 3; ix++ {
+			dur, err := bo.Next(true)
+			if dur != tc.dur {
+				t.Errorf("expected %v: %v", tc.dur, dur)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+
+			// Otherwise, the underlying BackOff is left alone
+			dur, err = bo.Next(false)
+			if dur != time.Second {
+				t.Errorf("expected %v: %v", time.Second, dur)
+			}
+			if err != nil {
+				t.Errorf("unexpected: %v", err)
+			}
+			_, err = bo.Next(false)
+			if !errors.Is(err, ErrStop) {
+				t.Errorf("expected %v: %v", ErrStop, err)
+			}
+		}
+	}
+
+	// Our own randomness is actually random, and stays in the window
+	bo := Spread(NewZero(), window, nil)
+	distinct := map[time.Duration]bool{}
+	for ix := 0; ix < 100; ix++ {
+		dur, err := bo.Next(true)
+		if dur < 0 || dur > window {
+			t.Errorf("out of range: %v", dur)
+		}
+		if err != nil {
+			t.Errorf("unexpected: %v", err)
+		}
+		distinct[dur] = true
+	}
+	if len(distinct) < 50 {
+		t.Errorf("not very random: %d", len(distinct))
+	}
+
+	// Check for non-sensical boundary condition
+	_, err := Spread(NewZero(), 0, nil).Next(true)
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+
+	// Errors during reset are left alone
+	_, err = Spread(Ceiling(NewZero(), 0), window, nil).Next(true)
+	if err != ErrLowBound {
+		t.Errorf("expected %v: %v", ErrLowBound, err)
+	}
+}
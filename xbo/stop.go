@@ -0,0 +1,90 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Reason explains why a BackOff said that no further attempts should be
+// made. Consumers writing their own BackOffs are free to declare their own.
+type Reason string
+
+// These are the Reasons given by the BackOffs in this package.
+const (
+	// ReasonStopped means the BackOff always says to stop (e.g. NewStop)
+	ReasonStopped Reason = "stopped"
+	// ReasonAttempts means too many attempts were made (e.g. MaxAttempts)
+	ReasonAttempts Reason = "too many attempts"
+	// ReasonElapsed means too much time has passed (e.g. Elapsed)
+	ReasonElapsed Reason = "too much time elapsed"
+	// ReasonExhausted means a finite sequence ran out (e.g. NewLimit)
+	ReasonExhausted Reason = "sequence exhausted"
+)
+
+// StopError is returned by the BackOffs in this package to say that no
+// further attempts should be made, and why. It satisfies
+// errors.Is(err, ErrStop), so consumers that don't care why can keep on
+// checking for ErrStop that way.
+type StopError struct {
+	// Reason says why the BackOff stopped
+	Reason Reason
+	// Source names the generator or decorator that stopped, e.g.
+	// "MaxAttempts"
+	Source string
+	// Attempts is how many attempts had been allowed since the last reset,
+	// or zero if the Source doesn't keep count
+	Attempts uint64
+	// Elapsed is how much time had passed since the last reset, or zero if
+	// the Source doesn't keep time
+	Elapsed time.Duration
+}
+
+func (e *StopError) Error() string {
+	msg := fmt.Sprintf("%v: %s", ErrStop, e.Reason)
+	if e.Source != "" {
+		msg += " in " + e.Source
+	}
+	if e.Attempts > 0 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if e.Elapsed > 0 {
+		msg += fmt.Sprintf(" (%v elapsed)", e.Elapsed)
+	}
+	return msg
+}
+
+// Is makes a StopError match ErrStop
+func (e *StopError) Is(target error) bool {
+	return target == ErrStop
+}
+
+// stopped passes along the StopError that one of our children returned, or
+// makes a new one (on behalf of the source) for any plain ErrStop
+func stopped(err error, source string) error {
+	var stop *StopError
+	if errors.As(err, &stop) {
+		return stop
+	}
+	return &StopError{Reason: ReasonStopped, Source: source}
+}
@@ -0,0 +1,112 @@
+// Copyright © 2017 Nelz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xbo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStopErrorReasons(t *testing.T) {
+	custom := BackOffFunc(func(reset bool) (time.Duration, error) {
+		return ZeroDuration, ErrStop
+	})
+
+	testCases := []struct {
+		bo       BackOff
+		reason   Reason
+		source   string
+		attempts uint64
+	}{
+		{NewStop(), ReasonStopped, "NewStop", 0},
+		{NewLimit([]time.Duration{time.Second}, false), ReasonExhausted, "NewLimit", 1},
+		{MaxAttempts(NewZero(), 1, false), ReasonAttempts, "MaxAttempts", 1},
+		{Elapsed(NewZero(), time.Nanosecond), ReasonElapsed, "Elapsed", 0},
+		// Combinators pass along the reason given by what they combine...
+		{Concat(false, NewLimit([]time.Duration{time.Second}, false)), ReasonExhausted, "NewLimit", 1},
+		{Max(StopOnAny, NewZero(), MaxAttempts(NewZero(), 1, false)), ReasonAttempts, "MaxAttempts", 1},
+		// ...unless there isn't one
+		{Concat(false, custom), ReasonStopped, "Concat", 0},
+		{Sum(StopOnAll, custom), ReasonStopped, "Sum", 0},
+	}
+
+	for ix, tc := range testCases {
+		// Move past the first delay, and past any time bound
+		tc.bo.Next(false)
+		time.Sleep(time.Millisecond)
+
+		_, err := tc.bo.Next(false)
+		if !errors.Is(err, ErrStop) {
+			t.Errorf("%d: expected %v: %v", ix, ErrStop, err)
+		}
+		var stop *StopError
+		if !errors.As(err, &stop) {
+			t.Fatalf("%d: expected StopError: %T", ix, err)
+		}
+		if stop.Reason != tc.reason {
+			t.Errorf("%d: expected %q: %q", ix, tc.reason, stop.Reason)
+		}
+		if stop.Source != tc.source {
+			t.Errorf("%d: expected %q: %q", ix, tc.source, stop.Source)
+		}
+		if stop.Attempts != tc.attempts {
+			t.Errorf("%d: expected %d: %d", ix, tc.attempts, stop.Attempts)
+		}
+	}
+}
+
+func TestStopErrorElapsed(t *testing.T) {
+	bo := Elapsed(NewZero(), time.Millisecond)
+	bo.Next(false)
+	time.Sleep(time.Millisecond * 2)
+
+	_, err := bo.Next(false)
+	var stop *StopError
+	if !errors.As(err, &stop) {
+		t.Fatalf("expected StopError: %T", err)
+	}
+	if stop.Elapsed <= time.Millisecond {
+		t.Errorf("expected more than %v: %v", time.Millisecond, stop.Elapsed)
+	}
+}
+
+func TestStopErrorWrapped(t *testing.T) {
+	err := fmt.Errorf("giving up: %w", &StopError{Reason: "custom", Source: "mine"})
+	if !errors.Is(err, ErrStop) {
+		t.Errorf("expected %v: %v", ErrStop, err)
+	}
+	if errors.Is(err, ErrLowBound) {
+		t.Errorf("unexpected match: %v", err)
+	}
+
+	expected := "giving up: stop any further attempts: custom in mine"
+	if err.Error() != expected {
+		t.Errorf("expected %q: %q", expected, err.Error())
+	}
+
+	stop := &StopError{Reason: ReasonElapsed, Source: "Elapsed", Attempts: 3, Elapsed: time.Second}
+	expected = "stop any further attempts: too much time elapsed in Elapsed after 3 attempts (1s elapsed)"
+	if stop.Error() != expected {
+		t.Errorf("expected %q: %q", expected, stop.Error())
+	}
+}
@@ -26,33 +26,111 @@ import (
 	"time"
 )
 
+// ErrDeadlineTooShort is the sentinel error returned by a deadline-aware
+// Waiter when the Context would expire before the next attempt could be made.
+var ErrDeadlineTooShort = fmt.Errorf("context deadline too short for next attempt")
+
 // NewWaiter produces a Waiter based off an underlying BackOff.
-func NewWaiter(bo BackOff) (*Waiter, error) {
+//
+// Use the functional WaiterOption to set other aspects of the behavior.
+func NewWaiter(bo BackOff, options ...WaiterOption) (*Waiter, error) {
 	if bo == nil {
 		return nil, fmt.Errorf("backoff must be defined")
 	}
-	return &Waiter{bo: bo}, nil
+
+	result := &Waiter{bo: bo}
+	for _, opt := range options {
+		err := opt(result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
 }
 
 // Waiter is a wrapper around a BackOff that will block
 // execution for the amount of time dictated by that BackOff.
 type Waiter struct {
-	bo BackOff
+	bo       BackOff
+	deadline bool
+	final    bool
+	margin   time.Duration
 }
 
 // Wait will interrogate the underlying BackOff for the expected
 // duration, and will then block for that amount of time. The user may send
-// in a Context for signalling early cancellation.
+// in a Context for signalling early cancellation. The Context is also handed
+// to the BackOff, if it is a ContextBackOff, and may override it altogether
+// (see NextContext).
 func (w Waiter) Wait(ctx context.Context, reset bool) error {
-	dur, err := w.bo.Next(reset)
+	_, err := w.wait(ctx, reset)
+	return err
+}
+
+// wait does the work of Wait, also reporting how long it waited
+func (w Waiter) wait(ctx context.Context, reset bool) (time.Duration, error) {
+	dur, err := NextContext(ctx, w.bo, reset)
 	if err != nil {
-		return err
+		return ZeroDuration, err
+	}
+
+	// There's no point in sleeping right up until the Context expires,
+	// if we already know the next attempt is not going to happen
+	if deadline, ok := ctx.Deadline(); ok && w.deadline {
+		now := time.Now()
+		if now.Add(dur).After(deadline) {
+			if !w.final {
+				return ZeroDuration, ErrDeadlineTooShort
+			}
+
+			// Squeeze in one last attempt, but only if there is
+			// still some time left on the clock for it
+			cutoff := deadline.Add(-w.margin)
+			if !cutoff.After(now) {
+				return ZeroDuration, ErrDeadlineTooShort
+			}
+			dur = cutoff.Sub(now)
+		}
 	}
+
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return ZeroDuration, ctx.Err()
 	case <-time.After(dur):
 		// Happy path
 	}
-	return nil
+	return dur, nil
+}
+
+// WaiterOption declares the functional options for changing behavior on
+// the created Waiter.
+type WaiterOption func(*Waiter) error
+
+// WaiterDeadline makes the Waiter consult the deadline of the Context sent
+// to Wait. If the next duration would run past that deadline, Wait returns
+// ErrDeadlineTooShort immediately, rather than blocking until the Context
+// expires.
+func WaiterDeadline(aware bool) WaiterOption {
+	return WaiterOption(func(w *Waiter) error {
+		w.deadline = aware
+		return nil
+	})
+}
+
+// WaiterFinalAttempt makes the Waiter deadline-aware (see WaiterDeadline),
+// but instead of giving up straight away when the next duration would run
+// past the deadline, it only waits until margin before the deadline so the
+// consumer can make one final attempt. Once there is no time left for that,
+// ErrDeadlineTooShort is returned.
+func WaiterFinalAttempt(margin time.Duration) WaiterOption {
+	return WaiterOption(func(w *Waiter) error {
+		if margin < 0 {
+			return fmt.Errorf("margin must not be negative: %v", margin)
+		}
+		w.deadline = true
+		w.final = true
+		w.margin = margin
+		return nil
+	})
 }
@@ -22,6 +22,7 @@ package xbo
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -72,7 +73,7 @@ func TestWaiterStop(t *testing.T) {
 		err = w.Wait(context.Background(), false)
 	}
 
-	if err != ErrStop {
+	if !errors.Is(err, ErrStop) {
 		t.Errorf("unexpected: %v", err)
 	}
 
@@ -80,3 +81,83 @@ func TestWaiterStop(t *testing.T) {
 		t.Errorf("expected 3 attempts: %d", attempts)
 	}
 }
+
+func TestNewWaiterOptionError(t *testing.T) {
+	w, err := NewWaiter(NewZero(), WaiterFinalAttempt(-time.Millisecond))
+	if err == nil {
+		t.Errorf("expected error")
+	}
+	if w != nil {
+		t.Errorf("unexpected: %v", w)
+	}
+}
+
+func TestWaiterDeadlineTooShort(t *testing.T) {
+	w, err := NewWaiter(NewConstant(time.Second*5), WaiterDeadline(true))
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	ctx, cxl := context.WithTimeout(
+		context.Background(),
+		time.Millisecond*250)
+	defer cxl()
+
+	start := time.Now()
+	err = w.Wait(ctx, false)
+	if err != ErrDeadlineTooShort {
+		t.Errorf("expected %v: %v", ErrDeadlineTooShort, err)
+	}
+
+	// We should have given up well before the Context expired
+	if elapsed := time.Since(start); elapsed > time.Millisecond*100 {
+		t.Errorf("expected immediate return: %s", elapsed)
+	}
+
+	// But a Context without a deadline doesn't get the same treatment
+	ctx, cxl = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		cxl()
+	}()
+	err = w.Wait(ctx, false)
+	if err != context.Canceled {
+		t.Errorf("expected %v: %v", context.Canceled, err)
+	}
+}
+
+func TestWaiterFinalAttempt(t *testing.T) {
+	w, err := NewWaiter(
+		NewConstant(time.Second*5),
+		WaiterFinalAttempt(time.Millisecond*100),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+
+	ctx, cxl := context.WithTimeout(
+		context.Background(),
+		time.Millisecond*300)
+	defer cxl()
+
+	attempts := 0
+	start := time.Now()
+	for err == nil {
+		attempts++
+		err = w.Wait(ctx, false)
+	}
+
+	if err != ErrDeadlineTooShort {
+		t.Errorf("expected %v: %v", ErrDeadlineTooShort, err)
+	}
+
+	// One real attempt, plus the final squeezed-in attempt
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts: %d", attempts)
+	}
+
+	// And that final attempt happened before the margin
+	if elapsed := time.Since(start); elapsed >= time.Millisecond*250 {
+		t.Errorf("expected to stop before the margin: %s", elapsed)
+	}
+}